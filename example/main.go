@@ -14,18 +14,29 @@ import (
 )
 
 func main() {
-	logProvider := otelprovider.NewLogger(
+	logProvider, err := otelprovider.NewLogger(
 		otelprovider.WithLogAutomaticEnv(),
 	)
+	if err != nil {
+		fmt.Printf("failed to initialize log exporters: %v\n", err)
+		if logProvider == nil {
+			os.Exit(1)
+		}
+	}
 
-	traceProvider := otelprovider.NewTracer(
+	traceProvider, err := otelprovider.NewTracer(
 		otelprovider.WithTraceAutomaticEnv(),
 	)
+	if err != nil {
+		fmt.Printf("failed to initialize trace exporters: %v\n", err)
+		if traceProvider == nil {
+			os.Exit(1)
+		}
+	}
 
 	// Initialize Logging
 	debug := os.Getenv("DEBUG") == "true"
 	var zapLogger *zap.Logger
-	var err error
 	if debug {
 		zapLogger, err = zap.NewDevelopment()
 	} else {
@@ -56,19 +67,7 @@ func main() {
 	undoOtelZapGlobals := otelzap.ReplaceGlobals(otelZapLogger)
 
 	defer func() {
-		if err := traceProvider.ForceFlush(context.Background()); err != nil {
-			otelzap.L().Warn("failed to flush traces")
-		}
-
-		if err := logProvider.ForceFlush(context.Background()); err != nil {
-			otelzap.L().Warn("failed to flush logs")
-		}
-
-		if err := traceProvider.Shutdown(context.Background()); err != nil {
-			panic(err)
-		}
-
-		if err := logProvider.Shutdown(context.Background()); err != nil {
+		if err := otelprovider.ShutdownAll(context.Background(), traceProvider, logProvider); err != nil {
 			panic(err)
 		}
 
@@ -0,0 +1,34 @@
+package otelprovider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// srvLookupTimeout bounds the lookup of the SRV record, so that an unreachable
+// DNS server can't block the startup of the application.
+const srvLookupTimeout = 5 * time.Second
+
+// lookupSRVEndpoint resolves the SRV record of the service, e.g.
+// "_otlp._tcp.collector.example.com", to the host:port of its target with the
+// lowest priority, picked by weight among those with the same priority.
+func lookupSRVEndpoint(ctx context.Context, service string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, srvLookupTimeout)
+	defer cancel()
+
+	_, addrs, err := net.DefaultResolver.LookupSRV(ctx, "", "", service)
+	if err != nil {
+		return "", err
+	}
+
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("no SRV records found for %s", service)
+	}
+
+	host := strings.TrimSuffix(addrs[0].Target, ".")
+	return net.JoinHostPort(host, strconv.Itoa(int(addrs[0].Port))), nil
+}
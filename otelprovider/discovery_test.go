@@ -0,0 +1,33 @@
+package otelprovider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestLookupSRVEndpointCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := lookupSRVEndpoint(ctx, "_otlp._tcp.collector.example.com")
+	assert.Error(t, err)
+}
+
+func TestSRVEndpointTakesPrecedenceOverEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4317")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=secret")
+
+	tracer := &Tracer{diagnostics: otelzap.New(zap.NewNop()), srvResolved: true}
+	tracer.applyAutomaticEnv()
+
+	assert.Empty(t, tracer.endpoints)
+	assert.Equal(t, map[string]string{"api-key": "secret"}, tracer.headers)
+
+	tracer.srvResolved = false
+	tracer.applyAutomaticEnv()
+	assert.Len(t, tracer.endpoints, 1)
+}
@@ -0,0 +1,207 @@
+package otelprovider
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.uber.org/zap"
+)
+
+const (
+	gzipCompression = "gzip"
+	noCompression   = "none"
+
+	grpcProtocol = "grpc"
+	httpProtocol = "http/protobuf"
+
+	// localhostGrpcEndpoint and localhostHttpEndpoint are the endpoints of a
+	// local collector, see WithTraceDefaultLocalhostEndpoint.
+	localhostGrpcEndpoint = "http://localhost:4317"
+	localhostHttpEndpoint = "http://localhost:4318"
+)
+
+// getenv returns the value of the environment variable with the given name,
+// preferring the variable prefixed with prefix (joined by an underscore) if
+// a prefix is set.
+func getenv(prefix, name string) string {
+	if prefix != "" {
+		if value, ok := os.LookupEnv(strings.TrimSuffix(prefix, "_") + "_" + name); ok {
+			return value
+		}
+	}
+	return os.Getenv(name)
+}
+
+// envEndpoint returns the OTLP endpoint for the signal ("logs", "traces" or
+// "metrics") from the environment. The signal-specific variable, e.g.
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, takes precedence and is used as is. The
+// path of a URL set with OTEL_EXPORTER_OTLP_ENDPOINT is the base path, to
+// which the signal path like "/v1/traces" is appended, as the spec defines.
+func envEndpoint(prefix, signal string) string {
+	if value := getenv(prefix, "OTEL_EXPORTER_OTLP_"+strings.ToUpper(signal)+"_ENDPOINT"); value != "" {
+		return value
+	}
+
+	value := getenv(prefix, "OTEL_EXPORTER_OTLP_ENDPOINT")
+	if !strings.Contains(value, "://") {
+		return value
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || strings.TrimSuffix(u.Path, "/") == "" {
+		return value // reported by parseEndpoint, or the default path applies
+	}
+
+	return u.JoinPath("v1", signal).String()
+}
+
+// envProtocol returns the OTLP protocol to export the signal to the endpoint
+// from the environment with, either grpcProtocol or httpProtocol. It is read
+// from the signal-specific variable, e.g. OTEL_EXPORTER_OTLP_TRACES_PROTOCOL,
+// falling back to OTEL_EXPORTER_OTLP_PROTOCOL. If neither is set, gRPC is used
+// for port 4317 and HTTP, the default of the spec, for any other port.
+func envProtocol(prefix, signal, endpointValue string, logger *otelzap.Logger) string {
+	protocol := getenv(prefix, "OTEL_EXPORTER_OTLP_"+strings.ToUpper(signal)+"_PROTOCOL")
+	if protocol == "" {
+		protocol = getenv(prefix, "OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+
+	switch protocol {
+	case grpcProtocol, httpProtocol:
+		return protocol
+	case "http/json":
+		logger.Warn("Unsupported OTLP protocol, exporting with http/protobuf", zap.String("protocol", protocol))
+		return httpProtocol
+	case "":
+	default:
+		logger.Warn("Unsupported OTLP protocol, choosing the protocol by port", zap.String("protocol", protocol))
+	}
+
+	if e, err := parseEndpoint(endpointValue); err == nil {
+		if _, port, err := net.SplitHostPort(e.hostPort); err == nil && port == "4317" {
+			return grpcProtocol
+		}
+	}
+	return httpProtocol
+}
+
+// envExporter returns the endpoint and the protocol to export the signal with,
+// see envProtocol. An empty endpoint is replaced by the local collector, at
+// port 4317 for gRPC, the default, or at port 4318 if the protocol variables
+// select HTTP.
+func envExporter(prefix, signal, endpointValue string, logger *otelzap.Logger) (string, string) {
+	if endpointValue != "" {
+		return endpointValue, envProtocol(prefix, signal, endpointValue, logger)
+	}
+
+	if envProtocol(prefix, signal, localhostGrpcEndpoint, logger) == grpcProtocol {
+		return localhostGrpcEndpoint, grpcProtocol
+	}
+	return localhostHttpEndpoint, httpProtocol
+}
+
+// parseHeaders parses headers in the format of OTEL_EXPORTER_OTLP_HEADERS: a
+// comma-separated list of key=value pairs with URL-encoded values, e.g.
+// "api-key=secret, authorization=Basic%20dXNlcjpwYXNz". Whitespace around the
+// separators is ignored and malformed pairs are skipped.
+func parseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+
+		key, err := url.PathUnescape(strings.TrimSpace(key))
+		if err != nil || key == "" {
+			continue
+		}
+
+		val, err = url.PathUnescape(strings.TrimSpace(val))
+		if err != nil {
+			continue
+		}
+
+		headers[key] = val
+	}
+
+	return headers
+}
+
+// mergeEnvHeaders adds the headers read from the environment to the
+// configured headers, without overriding the configured ones.
+func mergeEnvHeaders(headers map[string]string, envHeaders string) map[string]string {
+	if envHeaders == "" {
+		return headers
+	}
+
+	parsed := parseHeaders(envHeaders)
+	if headers == nil {
+		return parsed
+	}
+
+	for key, value := range parsed {
+		if _, ok := headers[key]; !ok {
+			headers[key] = value
+		}
+	}
+	return headers
+}
+
+// validCompression returns the compression if it is supported, and otherwise
+// logs a warning and returns no compression.
+func validCompression(compression string, logger *otelzap.Logger) string {
+	switch compression {
+	case "", gzipCompression, noCompression:
+		return compression
+	default:
+		logger.Warn("Unsupported OTLP compression, exporting uncompressed", zap.String("compression", compression))
+		return noCompression
+	}
+}
+
+// endpoint is an OTLP endpoint, parsed from either a plain host:port like
+// "localhost:4318" or a URL like "https://collector.example.com/otlp".
+type endpoint struct {
+	// hostPort is the host and port of the endpoint, without scheme.
+	hostPort string
+	// path is the URL path of the endpoint, empty if not given.
+	path string
+	// insecure is set for URLs with the http scheme.
+	insecure bool
+}
+
+// parseEndpoint parses the endpoint given to the endpoint options. A URL sets
+// the host, the URL path and, with the http scheme, an insecure connection,
+// while anything else is used as host:port as is.
+func parseEndpoint(value string) (endpoint, error) {
+	if !strings.Contains(value, "://") {
+		return endpoint{hostPort: value}, nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return endpoint{}, fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+	default:
+		return endpoint{}, fmt.Errorf("invalid OTLP endpoint %q: unsupported scheme %q", value, u.Scheme)
+	}
+
+	if u.Host == "" {
+		return endpoint{}, fmt.Errorf("invalid OTLP endpoint %q: missing host", value)
+	}
+
+	return endpoint{
+		hostPort: u.Host,
+		path:     strings.TrimSuffix(u.Path, "/"),
+		insecure: u.Scheme == "http",
+	}, nil
+}
@@ -0,0 +1,317 @@
+package otelprovider
+
+import (
+	"testing"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseHeaders(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		value string
+		want  map[string]string
+	}{
+		{name: "empty", value: "", want: map[string]string{}},
+		{name: "single", value: "api-key=secret", want: map[string]string{"api-key": "secret"}},
+		{
+			name:  "multiple",
+			value: "api-key=secret,tenant=acme",
+			want:  map[string]string{"api-key": "secret", "tenant": "acme"},
+		},
+		{
+			name:  "encoded value",
+			value: "authorization=Basic%20dXNlcjpwYXNz",
+			want:  map[string]string{"authorization": "Basic dXNlcjpwYXNz"},
+		},
+		{name: "encoded key", value: "x%2Dtenant=acme", want: map[string]string{"x-tenant": "acme"}},
+		{name: "value with equals sign", value: "token=a=b", want: map[string]string{"token": "a=b"}},
+		{
+			name:  "spaces",
+			value: " api-key = secret , tenant= acme ",
+			want:  map[string]string{"api-key": "secret", "tenant": "acme"},
+		},
+		{name: "empty value", value: "api-key=", want: map[string]string{"api-key": ""}},
+		{name: "empty pairs", value: ",api-key=secret,,", want: map[string]string{"api-key": "secret"}},
+		{name: "missing equals sign", value: "api-key,tenant=acme", want: map[string]string{"tenant": "acme"}},
+		{name: "empty key", value: "=secret,tenant=acme", want: map[string]string{"tenant": "acme"}},
+		{name: "invalid key escape", value: "api%zz=secret,tenant=acme", want: map[string]string{"tenant": "acme"}},
+		{name: "invalid value escape", value: "api-key=%zz,tenant=acme", want: map[string]string{"tenant": "acme"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseHeaders(tt.value))
+		})
+	}
+}
+
+func TestMergeEnvHeaders(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		headers    map[string]string
+		envHeaders string
+		want       map[string]string
+	}{
+		{name: "no env headers", headers: map[string]string{"a": "1"}, envHeaders: "", want: map[string]string{"a": "1"}},
+		{name: "no configured headers", headers: nil, envHeaders: "a=1", want: map[string]string{"a": "1"}},
+		{name: "nothing set", headers: nil, envHeaders: "", want: nil},
+		{
+			name:       "merged",
+			headers:    map[string]string{"a": "1"},
+			envHeaders: "b=2",
+			want:       map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:       "configured take precedence",
+			headers:    map[string]string{"a": "1"},
+			envHeaders: "a=2,b=2",
+			want:       map[string]string{"a": "1", "b": "2"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mergeEnvHeaders(tt.headers, tt.envHeaders))
+		})
+	}
+}
+
+func TestValidCompression(t *testing.T) {
+	for _, tt := range []struct {
+		compression string
+		want        string
+		warned      bool
+	}{
+		{compression: "", want: ""},
+		{compression: "gzip", want: "gzip"},
+		{compression: "none", want: "none"},
+		{compression: "zstd", want: "none", warned: true},
+	} {
+		t.Run(tt.compression, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			logger := otelzap.New(zap.New(core))
+
+			assert.Equal(t, tt.want, validCompression(tt.compression, logger))
+			if tt.warned {
+				assert.Equal(t, 1, logs.FilterLevelExact(zapcore.WarnLevel).Len())
+			} else {
+				assert.Zero(t, logs.Len())
+			}
+		})
+	}
+}
+
+func TestParseEndpoint(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		value   string
+		want    endpoint
+		wantErr bool
+	}{
+		{name: "host and port", value: "localhost:4318", want: endpoint{hostPort: "localhost:4318"}},
+		{name: "host", value: "collector", want: endpoint{hostPort: "collector"}},
+		{
+			name:  "http",
+			value: "http://collector:4318",
+			want:  endpoint{hostPort: "collector:4318", insecure: true},
+		},
+		{
+			name:  "http with path",
+			value: "http://collector:4318/otlp/v1/logs",
+			want:  endpoint{hostPort: "collector:4318", path: "/otlp/v1/logs", insecure: true},
+		},
+		{
+			name:  "https",
+			value: "https://collector.example.com",
+			want:  endpoint{hostPort: "collector.example.com"},
+		},
+		{
+			name:  "https with path",
+			value: "https://collector.example.com/v1/traces",
+			want:  endpoint{hostPort: "collector.example.com", path: "/v1/traces"},
+		},
+		{
+			name:  "trailing slash",
+			value: "https://collector.example.com:4318/",
+			want:  endpoint{hostPort: "collector.example.com:4318"},
+		},
+		{name: "unsupported scheme", value: "grpc://collector:4317", wantErr: true},
+		{name: "missing host", value: "http:///v1/logs", wantErr: true},
+		{name: "invalid URL", value: "http://%zz", wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEndpoint(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEnvEndpoint(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		env     map[string]string
+		prefix  string
+		traces  string
+		metrics string
+	}{
+		{name: "not set", env: map[string]string{}},
+		{
+			name:    "generic host and port",
+			env:     map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "localhost:4317"},
+			traces:  "localhost:4317",
+			metrics: "localhost:4317",
+		},
+		{
+			name:    "generic URL without path",
+			env:     map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318/"},
+			traces:  "http://collector:4318/",
+			metrics: "http://collector:4318/",
+		},
+		{
+			name:    "generic URL with path",
+			env:     map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318/otlp"},
+			traces:  "http://collector:4318/otlp/v1/traces",
+			metrics: "http://collector:4318/otlp/v1/metrics",
+		},
+		{
+			name: "signal-specific takes precedence",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://collector:4318/otlp",
+				"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "https://traces.example.com/custom",
+			},
+			traces:  "https://traces.example.com/custom",
+			metrics: "http://collector:4318/otlp/v1/metrics",
+		},
+		{
+			name: "prefixed",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":             "localhost:4317",
+				"ACME_OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "localhost:4318",
+			},
+			prefix:  "ACME",
+			traces:  "localhost:4318",
+			metrics: "localhost:4317",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+				"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", "ACME_OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+			} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			assert.Equal(t, tt.traces, envEndpoint(tt.prefix, "traces"))
+			assert.Equal(t, tt.metrics, envEndpoint(tt.prefix, "metrics"))
+		})
+	}
+}
+
+func TestEnvProtocol(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		env      map[string]string
+		endpoint string
+		want     string
+		warned   bool
+	}{
+		{name: "gRPC port", endpoint: "localhost:4317", want: grpcProtocol},
+		{name: "HTTP port", endpoint: "localhost:4318", want: httpProtocol},
+		{name: "gRPC port in URL", endpoint: "http://collector:4317", want: grpcProtocol},
+		{name: "no port", endpoint: "https://collector.example.com/v1/traces", want: httpProtocol},
+		{name: "other port", endpoint: "collector:9000", want: httpProtocol},
+		{
+			name:     "generic protocol",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"},
+			endpoint: "https://collector.example.com",
+			want:     grpcProtocol,
+		},
+		{
+			name: "signal-specific protocol takes precedence",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_PROTOCOL":        "grpc",
+				"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL": "http/protobuf",
+			},
+			endpoint: "localhost:4317",
+			want:     httpProtocol,
+		},
+		{
+			name:     "http/json",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "http/json"},
+			endpoint: "localhost:4317",
+			want:     httpProtocol,
+			warned:   true,
+		},
+		{
+			name:     "unsupported protocol",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "thrift"},
+			endpoint: "localhost:4317",
+			want:     grpcProtocol,
+			warned:   true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"OTEL_EXPORTER_OTLP_PROTOCOL", "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			core, logs := observer.New(zapcore.DebugLevel)
+			logger := otelzap.New(zap.New(core))
+
+			assert.Equal(t, tt.want, envProtocol("", "traces", tt.endpoint, logger))
+			if tt.warned {
+				assert.Equal(t, 1, logs.FilterLevelExact(zapcore.WarnLevel).Len())
+			} else {
+				assert.Zero(t, logs.Len())
+			}
+		})
+	}
+}
+
+func TestEnvExporter(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		env          map[string]string
+		endpoint     string
+		wantEndpoint string
+		wantProtocol string
+	}{
+		{name: "endpoint set", endpoint: "localhost:4318", wantEndpoint: "localhost:4318", wantProtocol: httpProtocol},
+		{name: "local gRPC collector", wantEndpoint: localhostGrpcEndpoint, wantProtocol: grpcProtocol},
+		{
+			name:         "local HTTP collector",
+			env:          map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "http/protobuf"},
+			wantEndpoint: localhostHttpEndpoint,
+			wantProtocol: httpProtocol,
+		},
+		{
+			name:         "local HTTP collector for the signal",
+			env:          map[string]string{"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL": "http/protobuf"},
+			wantEndpoint: localhostHttpEndpoint,
+			wantProtocol: httpProtocol,
+		},
+		{
+			name:         "local gRPC collector by protocol",
+			env:          map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"},
+			wantEndpoint: localhostGrpcEndpoint,
+			wantProtocol: grpcProtocol,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"OTEL_EXPORTER_OTLP_PROTOCOL", "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			endpoint, protocol := envExporter("", "traces", tt.endpoint, otelzap.New(zap.NewNop()))
+			assert.Equal(t, tt.wantEndpoint, endpoint)
+			assert.Equal(t, tt.wantProtocol, protocol)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package otelprovider
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/log"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// fallbackWindow and fallbackMaxRecords rate-limit the records written by
+	// the stdout fallback to at most fallbackMaxRecords per fallbackWindow.
+	fallbackWindow     = time.Minute
+	fallbackMaxRecords = 1000
+)
+
+// fallbackExporter writes the records the wrapped exporter failed to export to
+// a writer, one OTLP-JSON encoded ExportLogsServiceRequest per line.
+type fallbackExporter struct {
+	log.Exporter
+
+	mu          sync.Mutex
+	w           io.Writer
+	windowStart time.Time
+	written     int
+}
+
+func newFallbackExporter(exporter log.Exporter, w io.Writer) *fallbackExporter {
+	return &fallbackExporter{Exporter: exporter, w: w}
+}
+
+func (e *fallbackExporter) Export(ctx context.Context, records []log.Record) error {
+	err := e.Exporter.Export(ctx, records)
+	if err == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(e.windowStart) >= fallbackWindow {
+		e.windowStart = now
+		e.written = 0
+	}
+
+	if n := fallbackMaxRecords - e.written; len(records) > n {
+		records = records[:n]
+	}
+	if len(records) == 0 {
+		return err
+	}
+	e.written += len(records)
+
+	if line, marshalErr := protojson.Marshal(otlpLogsRequest(records)); marshalErr == nil {
+		_, _ = e.w.Write(append(line, '\n'))
+	}
+
+	return err
+}
+
+// otlpLogsRequest converts the records to their OTLP representation. All
+// records are assumed to share the resource of the first one, as they
+// originate from the same LoggerProvider.
+func otlpLogsRequest(records []log.Record) *collogspb.ExportLogsServiceRequest {
+	res := records[0].Resource()
+	resourceLogs := &logspb.ResourceLogs{
+		Resource:  &resourcepb.Resource{Attributes: otlpAttributes(res.Attributes())},
+		SchemaUrl: res.SchemaURL(),
+	}
+
+	scopes := make(map[instrumentation.Scope]*logspb.ScopeLogs)
+	for i := range records {
+		r := &records[i]
+
+		scope := r.InstrumentationScope()
+		scopeLogs, ok := scopes[scope]
+		if !ok {
+			scopeLogs = &logspb.ScopeLogs{
+				Scope:     &commonpb.InstrumentationScope{Name: scope.Name, Version: scope.Version},
+				SchemaUrl: scope.SchemaURL,
+			}
+			scopes[scope] = scopeLogs
+			resourceLogs.ScopeLogs = append(resourceLogs.ScopeLogs, scopeLogs)
+		}
+
+		scopeLogs.LogRecords = append(scopeLogs.LogRecords, otlpLogRecord(r))
+	}
+
+	return &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{resourceLogs}}
+}
+
+func otlpLogRecord(r *log.Record) *logspb.LogRecord {
+	record := &logspb.LogRecord{
+		TimeUnixNano:           unixNano(r.Timestamp()),
+		ObservedTimeUnixNano:   unixNano(r.ObservedTimestamp()),
+		SeverityNumber:         logspb.SeverityNumber(r.Severity()),
+		SeverityText:           r.SeverityText(),
+		Body:                   otlpLogValue(r.Body()),
+		DroppedAttributesCount: uint32(r.DroppedAttributes()),
+		Flags:                  uint32(r.TraceFlags()),
+	}
+
+	if traceID := r.TraceID(); traceID.IsValid() {
+		record.TraceId = traceID[:]
+	}
+	if spanID := r.SpanID(); spanID.IsValid() {
+		record.SpanId = spanID[:]
+	}
+
+	r.WalkAttributes(func(kv otellog.KeyValue) bool {
+		record.Attributes = append(record.Attributes, &commonpb.KeyValue{Key: kv.Key, Value: otlpLogValue(kv.Value)})
+		return true
+	})
+
+	return record
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func otlpLogValue(v otellog.Value) *commonpb.AnyValue {
+	switch v.Kind() {
+	case otellog.KindBool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case otellog.KindInt64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case otellog.KindFloat64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case otellog.KindString:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	case otellog.KindBytes:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v.AsBytes()}}
+	case otellog.KindSlice:
+		values := make([]*commonpb.AnyValue, 0, len(v.AsSlice()))
+		for _, item := range v.AsSlice() {
+			values = append(values, otlpLogValue(item))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case otellog.KindMap:
+		kvs := make([]*commonpb.KeyValue, 0, len(v.AsMap()))
+		for _, kv := range v.AsMap() {
+			kvs = append(kvs, &commonpb.KeyValue{Key: kv.Key, Value: otlpLogValue(kv.Value)})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: kvs}}}
+	default:
+		return nil
+	}
+}
+
+func otlpAttributes(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	kvs := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		kvs = append(kvs, &commonpb.KeyValue{Key: string(kv.Key), Value: otlpAttributeValue(kv.Value)})
+	}
+	return kvs
+}
+
+func otlpAttributeValue(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.STRING:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	case attribute.BOOLSLICE:
+		return otlpArrayValue(v.AsBoolSlice(), attribute.BoolValue)
+	case attribute.INT64SLICE:
+		return otlpArrayValue(v.AsInt64Slice(), attribute.Int64Value)
+	case attribute.FLOAT64SLICE:
+		return otlpArrayValue(v.AsFloat64Slice(), attribute.Float64Value)
+	case attribute.STRINGSLICE:
+		return otlpArrayValue(v.AsStringSlice(), attribute.StringValue)
+	default:
+		return nil
+	}
+}
+
+func otlpArrayValue[T any](items []T, value func(T) attribute.Value) *commonpb.AnyValue {
+	values := make([]*commonpb.AnyValue, 0, len(items))
+	for _, item := range items {
+		values = append(values, otlpAttributeValue(value(item)))
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+}
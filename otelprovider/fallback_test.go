@@ -0,0 +1,120 @@
+package otelprovider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var errExportFailed = errors.New("export failed")
+
+// failingExporter fails to export any record.
+type failingExporter struct{}
+
+func (failingExporter) Export(context.Context, []log.Record) error { return errExportFailed }
+func (failingExporter) Shutdown(context.Context) error             { return nil }
+func (failingExporter) ForceFlush(context.Context) error           { return nil }
+
+// succeedingExporter exports all records successfully.
+type succeedingExporter struct {
+	failingExporter
+}
+
+func (succeedingExporter) Export(context.Context, []log.Record) error { return nil }
+
+func newFallbackLogger(t *testing.T, buf *bytes.Buffer) otellog.Logger {
+	t.Helper()
+
+	exporter := newFallbackExporter(failingExporter{}, buf)
+	provider := log.NewLoggerProvider(
+		log.WithResource(resource.NewSchemaless(semconv.ServiceName("checkout"))),
+		log.WithProcessor(log.NewSimpleProcessor(exporter)),
+	)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	return provider.Logger("fallback-test", otellog.WithInstrumentationVersion("1.2.3"))
+}
+
+func TestFallbackExporterWritesOTLPJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newFallbackLogger(t, &buf)
+
+	var record otellog.Record
+	record.SetBody(otellog.StringValue("Test Message"))
+	record.SetSeverity(otellog.SeverityError)
+	record.SetSeverityText("error")
+	record.AddAttributes(otellog.Int("count", 42), otellog.Map("user", otellog.String("id", "u-1")))
+	logger.Emit(context.Background(), record)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 1)
+
+	var request collogspb.ExportLogsServiceRequest
+	require.NoError(t, protojson.Unmarshal([]byte(lines[0]), &request))
+	require.Len(t, request.ResourceLogs, 1)
+
+	resourceLogs := request.ResourceLogs[0]
+	require.Len(t, resourceLogs.Resource.Attributes, 1)
+	assert.Equal(t, "service.name", resourceLogs.Resource.Attributes[0].Key)
+	assert.Equal(t, "checkout", resourceLogs.Resource.Attributes[0].Value.GetStringValue())
+
+	require.Len(t, resourceLogs.ScopeLogs, 1)
+	assert.Equal(t, "fallback-test", resourceLogs.ScopeLogs[0].Scope.Name)
+	assert.Equal(t, "1.2.3", resourceLogs.ScopeLogs[0].Scope.Version)
+
+	require.Len(t, resourceLogs.ScopeLogs[0].LogRecords, 1)
+	logRecord := resourceLogs.ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, "Test Message", logRecord.Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, logRecord.SeverityNumber)
+	assert.Equal(t, "error", logRecord.SeverityText)
+	assert.NotZero(t, logRecord.ObservedTimeUnixNano)
+
+	require.Len(t, logRecord.Attributes, 2)
+	assert.Equal(t, "count", logRecord.Attributes[0].Key)
+	assert.Equal(t, int64(42), logRecord.Attributes[0].Value.GetIntValue())
+	assert.Equal(t, "user", logRecord.Attributes[1].Key)
+	user := logRecord.Attributes[1].Value.GetKvlistValue().GetValues()
+	require.Len(t, user, 1)
+	assert.Equal(t, "u-1", user[0].Value.GetStringValue())
+}
+
+func TestFallbackExporterPassesSuccessfulExports(t *testing.T) {
+	var buf bytes.Buffer
+	exporter := newFallbackExporter(succeedingExporter{}, &buf)
+
+	require.NoError(t, exporter.Export(context.Background(), make([]log.Record, 1)))
+	assert.Zero(t, buf.Len())
+}
+
+func TestFallbackExporterRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	exporter := newFallbackExporter(failingExporter{}, &buf)
+
+	for i := 0; i < 3; i++ {
+		err := exporter.Export(context.Background(), make([]log.Record, 600))
+		assert.ErrorIs(t, err, errExportFailed)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	written := 0
+	for _, line := range lines {
+		var request collogspb.ExportLogsServiceRequest
+		require.NoError(t, protojson.Unmarshal([]byte(line), &request))
+		written += len(request.ResourceLogs[0].ScopeLogs[0].LogRecords)
+	}
+	assert.Equal(t, fallbackMaxRecords, written)
+}
@@ -0,0 +1,82 @@
+package otelprovider
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/sdk/log"
+)
+
+// flushEveryNProcessor wraps a processor to force a flush after every n
+// emitted records, in addition to the flushes of the wrapped processor.
+type flushEveryNProcessor struct {
+	log.Processor
+
+	n        uint64
+	emitted  atomic.Uint64
+	flushing atomic.Bool
+
+	// mu guards starting a background flush against Shutdown, which waits for
+	// the running one with flushes.
+	mu       sync.Mutex
+	shutdown bool
+	flushes  sync.WaitGroup
+}
+
+func newFlushEveryNProcessor(processor log.Processor, n int) *flushEveryNProcessor {
+	return &flushEveryNProcessor{Processor: processor, n: uint64(n)}
+}
+
+func (p *flushEveryNProcessor) OnEmit(ctx context.Context, record *log.Record) error {
+	err := p.Processor.OnEmit(ctx, record)
+
+	// Flush in the background so the log call doesn't wait for the export,
+	// and skip the flush if the previous one is still running.
+	if p.emitted.Add(1)%p.n == 0 && p.flushing.CompareAndSwap(false, true) {
+		p.flushInBackground()
+	}
+
+	return err
+}
+
+// flushInBackground starts a flush unless the processor is shut down. It must
+// only be called by the caller that set p.flushing.
+func (p *flushEveryNProcessor) flushInBackground() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.shutdown {
+		p.flushing.Store(false)
+		return
+	}
+
+	p.flushes.Add(1)
+	go func() {
+		defer p.flushes.Done()
+		defer p.flushing.Store(false)
+		_ = p.Processor.ForceFlush(context.Background())
+	}()
+}
+
+// Shutdown waits for the running background flush, if any, before shutting
+// down the wrapped processor, and stops starting new ones.
+func (p *flushEveryNProcessor) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.shutdown = true
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.flushes.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return p.Processor.Shutdown(ctx)
+}
@@ -0,0 +1,90 @@
+package otelprovider
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/log"
+)
+
+// blockingFlushProcessor counts the flushes, which block until released.
+type blockingFlushProcessor struct {
+	release  chan struct{}
+	flushes  atomic.Int64
+	running  atomic.Int64
+	overlaps atomic.Int64
+	shutdown atomic.Bool
+}
+
+func (p *blockingFlushProcessor) OnEmit(context.Context, *log.Record) error { return nil }
+func (p *blockingFlushProcessor) Enabled(context.Context, log.Record) bool  { return true }
+
+func (p *blockingFlushProcessor) ForceFlush(context.Context) error {
+	if p.running.Add(1) > 1 {
+		p.overlaps.Add(1)
+	}
+	defer p.running.Add(-1)
+
+	p.flushes.Add(1)
+	<-p.release
+	return nil
+}
+
+func (p *blockingFlushProcessor) Shutdown(context.Context) error {
+	if p.running.Load() > 0 {
+		p.overlaps.Add(1)
+	}
+	p.shutdown.Store(true)
+	return nil
+}
+
+func TestFlushEveryN(t *testing.T) {
+	next := &blockingFlushProcessor{release: make(chan struct{})}
+	processor := newFlushEveryNProcessor(next, 2)
+
+	var record log.Record
+	for i := 0; i < 6; i++ {
+		require.NoError(t, processor.OnEmit(context.Background(), &record))
+	}
+
+	// The first flush is still running, so the later ones are skipped.
+	assert.Eventually(t, func() bool { return next.flushes.Load() == 1 }, time.Second, time.Millisecond)
+
+	shutdown := make(chan error)
+	go func() { shutdown <- processor.Shutdown(context.Background()) }()
+
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned before the running flush")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(next.release)
+	require.NoError(t, <-shutdown)
+	assert.True(t, next.shutdown.Load())
+	assert.Zero(t, next.overlaps.Load())
+
+	// No flushes are started after shutdown.
+	for i := 0; i < 2; i++ {
+		require.NoError(t, processor.OnEmit(context.Background(), &record))
+	}
+	assert.Equal(t, int64(1), next.flushes.Load())
+}
+
+func TestFlushEveryNShutdownTimeout(t *testing.T) {
+	next := &blockingFlushProcessor{release: make(chan struct{})}
+	defer close(next.release)
+	processor := newFlushEveryNProcessor(next, 1)
+
+	var record log.Record
+	require.NoError(t, processor.OnEmit(context.Background(), &record))
+	assert.Eventually(t, func() bool { return next.flushes.Load() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, processor.Shutdown(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,78 @@
+package otelprovider
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// serviceAccountNamespaceFile holds the namespace of the pod, mounted into
+// every pod with a service account token.
+var serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// kubernetesDetector detects the pod, namespace and node the process runs in
+// from the environment variables commonly set with the downward API:
+//
+//	env:
+//	  - name: POD_NAME
+//	    valueFrom: {fieldRef: {fieldPath: metadata.name}}
+//	  - name: POD_NAMESPACE
+//	    valueFrom: {fieldRef: {fieldPath: metadata.namespace}}
+//	  - name: NODE_NAME
+//	    valueFrom: {fieldRef: {fieldPath: spec.nodeName}}
+//
+// Without POD_NAMESPACE, the namespace is read from the service account.
+// Attributes that are not available are left out.
+type kubernetesDetector struct{}
+
+func (kubernetesDetector) Detect(context.Context) (*resource.Resource, error) {
+	var attrs []attribute.KeyValue
+
+	if name := os.Getenv("POD_NAME"); name != "" {
+		attrs = append(attrs, semconv.K8SPodName(name))
+	}
+
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		if b, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+			namespace = strings.TrimSpace(string(b))
+		}
+	}
+	if namespace != "" {
+		attrs = append(attrs, semconv.K8SNamespaceName(namespace))
+	}
+
+	if node := os.Getenv("NODE_NAME"); node != "" {
+		attrs = append(attrs, semconv.K8SNodeName(node))
+	}
+
+	if len(attrs) == 0 {
+		return resource.Empty(), nil
+	}
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...), nil
+}
+
+// WithLogKubernetesResource adds the k8s.pod.name, k8s.namespace.name and
+// k8s.node.name attributes to the resource, read from the POD_NAME,
+// POD_NAMESPACE and NODE_NAME environment variables set with the downward API.
+// Without POD_NAMESPACE, the namespace is read from the service account.
+// Attributes that are not available are left out.
+func WithLogKubernetesResource() LoggerOption {
+	return WithLogResourceDetectors(kubernetesDetector{})
+}
+
+// WithTraceKubernetesResource adds the k8s.pod.name, k8s.namespace.name and
+// k8s.node.name attributes to the resource, see WithLogKubernetesResource.
+func WithTraceKubernetesResource() TracerOption {
+	return WithTraceResourceDetectors(kubernetesDetector{})
+}
+
+// WithMetricKubernetesResource adds the k8s.pod.name, k8s.namespace.name and
+// k8s.node.name attributes to the resource, see WithLogKubernetesResource.
+func WithMetricKubernetesResource() MeterOption {
+	return WithMetricResourceDetectors(kubernetesDetector{})
+}
@@ -2,38 +2,126 @@ package otelprovider
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
 	"go.opentelemetry.io/otel/log/global"
 	"go.opentelemetry.io/otel/sdk/log"
 	"go.opentelemetry.io/otel/sdk/resource"
-	"go.uber.org/zap"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
 )
 
+// grpcMinConnectTimeout mirrors gRPC's default minimum connect timeout, which
+// would otherwise be reset when overriding the connect backoff.
+const grpcMinConnectTimeout = 20 * time.Second
+
+// exporterSetupTimeout bounds the creation of an exporter, so that a
+// misconfigured endpoint can't block the startup of the application.
+const exporterSetupTimeout = 10 * time.Second
+
 type Logger struct {
 	providerOptions []log.LoggerProviderOption
 	insecure        bool
 	resources       *resource.Resource
-	register        bool
+	detectors       bool
+	// resourceDetectors are the additional detectors set with
+	// WithLogResourceDetectors.
+	resourceDetectors []resource.Detector
+	register          bool
+
+	// endpoints create the exporters once all options have been applied,
+	// so that the order in which options are passed does not matter.
+	endpoints          []LoggerOption
+	grpcConnectBackoff *backoff.Config
+	retry              *RetryConfig
+	exportTimeout      time.Duration
+	resourceAttributes []attribute.KeyValue
+	stdoutFallback     bool
+	automaticEnv       bool
+	envPrefix          string
+	defaultLocalhost   bool
+	headers            map[string]string
+	tlsConfig          *tls.Config
+	compression        string
+	flushEvery         int
+	batchOptions       []log.BatchProcessorOption
+
+	// diagnostics logs the problems with the configuration, see
+	// WithLogDiagnosticLogger.
+	diagnostics *otelzap.Logger
+
+	// errs collects the errors of creating the exporters.
+	errs []error
 }
 
-func NewLogger(opts ...LoggerOption) *log.LoggerProvider {
+// NewLogger creates a LoggerProvider exporting the log records to the
+// configured endpoints, and registers it globally unless
+// WithoutRegisterLogProvider is given.
+//
+// Failing to create an exporter, e.g. due to an invalid endpoint, does not keep
+// the provider from being created: the exporter is left out and the error is
+// returned along with the provider, which the application may keep using.
+// If the resource describing the service can't be created, no provider is
+// created and the error is returned.
+func NewLogger(opts ...LoggerOption) (*log.LoggerProvider, error) {
 	l := &Logger{
 		insecure:        false,
 		providerOptions: []log.LoggerProviderOption{},
-		resources:       newOtelResources(),
+		detectors:       true,
 		register:        true,
+		exportTimeout:   10 * time.Second,
 	}
 
 	for _, opt := range opts {
 		opt(l)
 	}
 
+	if l.diagnostics == nil {
+		l.diagnostics = otelzap.L()
+	}
+
+	if l.resources == nil {
+		resources, err := cachedOtelResources(l.detectors, l.diagnostics)
+		if err != nil {
+			return nil, err
+		}
+		l.resources = resources
+	}
+
+	if len(l.resourceDetectors) > 0 {
+		resources, err := mergeDetectedResource(l.resources, l.resourceDetectors, l.diagnostics)
+		if err != nil {
+			return nil, err
+		}
+		l.resources = resources
+	}
+
+	if l.automaticEnv {
+		l.applyAutomaticEnv()
+	}
+
+	if l.tlsConfig != nil && l.insecure {
+		l.diagnostics.Warn("Both a TLS config and an insecure connection are configured for the OTLP log exporters, using the TLS config")
+		l.insecure = false
+	}
+
+	l.compression = validCompression(l.compression, l.diagnostics)
+
+	for _, endpoint := range l.endpoints {
+		endpoint(l)
+	}
+
+	l.resources = mergeResourceAttributes(l.resources, l.resourceAttributes, l.diagnostics)
 	l.providerOptions = append(l.providerOptions, log.WithResource(l.resources))
 	logProvider := log.NewLoggerProvider(l.providerOptions...)
 
@@ -42,7 +130,28 @@ func NewLogger(opts ...LoggerOption) *log.LoggerProvider {
 		global.SetLoggerProvider(logProvider)
 	}
 
-	return logProvider
+	return logProvider, errors.Join(l.errs...)
+}
+
+func (t *Logger) newBatchProcessor(exporter log.Exporter) log.Processor {
+	if t.stdoutFallback {
+		exporter = newFallbackExporter(exporter, os.Stderr)
+	}
+
+	batchOptions := append([]log.BatchProcessorOption{
+		log.WithMaxQueueSize(10_000),
+		log.WithExportMaxBatchSize(10_000),
+		log.WithExportInterval(10 * time.Second),
+		log.WithExportTimeout(t.exportTimeout),
+	}, t.batchOptions...)
+
+	var processor log.Processor = log.NewBatchProcessor(exporter, batchOptions...)
+
+	if t.flushEvery > 0 {
+		processor = newFlushEveryNProcessor(processor, t.flushEvery)
+	}
+
+	return processor
 }
 
 // TracerOption applies a configuration to the given config.
@@ -54,76 +163,277 @@ func WithLogInsecure() LoggerOption {
 	}
 }
 
+// WithLogTLSConfig sets the TLS configuration of the connection to the
+// collector, e.g. to trust a private CA. It takes precedence over
+// WithLogInsecure, with a warning logged if both are set.
+func WithLogTLSConfig(config *tls.Config) LoggerOption {
+	return func(t *Logger) {
+		t.tlsConfig = config
+	}
+}
+
+// WithGrpcLogEndpoint exports the log records to the OTLP gRPC endpoint, e.g.
+// "localhost:4317". Endpoint options accumulate: every endpoint, including the
+// ones added by WithHttpLogEndpoint, WithLogStdout and WithLogAutomaticEnv,
+// gets its own exporter and batch processor. The endpoint may also be given
+// as URL, e.g. "http://localhost:4317", with the http scheme making the
+// connection insecure.
 func WithGrpcLogEndpoint(otelGrpcEndpoint string) LoggerOption {
 	return func(t *Logger) {
+		t.endpoints = append(t.endpoints, grpcLogEndpoint(otelGrpcEndpoint))
+	}
+}
+
+func grpcLogEndpoint(otelGrpcEndpoint string) LoggerOption {
+	return func(t *Logger) {
+		endpoint, err := parseEndpoint(otelGrpcEndpoint)
+		if err != nil {
+			t.errs = append(t.errs, err)
+			return
+		}
+
 		grpcExporterOptions := []otlploggrpc.Option{
-			otlploggrpc.WithEndpoint(otelGrpcEndpoint),
+			otlploggrpc.WithEndpoint(endpoint.hostPort),
 		}
 
-		if t.insecure {
+		if t.insecure || endpoint.insecure {
 			grpcExporterOptions = append(grpcExporterOptions, otlploggrpc.WithInsecure())
 		}
 
-		grpcExporter, err := otlploggrpc.New(context.Background(), grpcExporterOptions...)
-		if err != nil {
-			otelzap.L().Sugar().Fatalw("Failed to create OTLP gRPC logs exporter", zap.Error(err))
+		if t.compression == gzipCompression {
+			grpcExporterOptions = append(grpcExporterOptions, otlploggrpc.WithCompressor(gzipCompression))
+		}
+
+		if t.tlsConfig != nil {
+			grpcExporterOptions = append(grpcExporterOptions, otlploggrpc.WithTLSCredentials(credentials.NewTLS(t.tlsConfig)))
 		}
 
-		batcher := log.NewBatchProcessor(grpcExporter,
-			log.WithMaxQueueSize(10_000),
-			log.WithExportMaxBatchSize(10_000),
-			log.WithExportInterval(10*time.Second),
-			log.WithExportTimeout(10*time.Second),
-		)
+		if len(t.headers) > 0 {
+			grpcExporterOptions = append(grpcExporterOptions, otlploggrpc.WithHeaders(t.headers))
+		}
 
-		t.providerOptions = append(t.providerOptions, log.WithProcessor(batcher))
+		if t.grpcConnectBackoff != nil {
+			grpcExporterOptions = append(grpcExporterOptions, otlploggrpc.WithDialOption(
+				grpc.WithConnectParams(grpc.ConnectParams{
+					Backoff:           *t.grpcConnectBackoff,
+					MinConnectTimeout: grpcMinConnectTimeout,
+				}),
+			))
+		}
+
+		if t.retry != nil {
+			grpcExporterOptions = append(grpcExporterOptions, otlploggrpc.WithRetry(otlploggrpc.RetryConfig(t.retry.exporterConfig())))
+		}
+
+		if t.exportTimeout > 0 {
+			grpcExporterOptions = append(grpcExporterOptions, otlploggrpc.WithTimeout(t.exportTimeout))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), exporterSetupTimeout)
+		defer cancel()
+
+		grpcExporter, err := otlploggrpc.New(ctx, grpcExporterOptions...)
+		if err != nil {
+			t.errs = append(t.errs, fmt.Errorf("failed to create OTLP gRPC logs exporter: %w", err))
+			return
+		}
+
+		t.providerOptions = append(t.providerOptions, log.WithProcessor(t.newBatchProcessor(grpcExporter)))
 	}
 }
 
+// WithHttpLogEndpoint exports the log records to the OTLP HTTP endpoint, e.g.
+// "localhost:4318". Like all endpoint options, it adds an exporter rather than
+// replacing the ones configured by other options. The endpoint may also be
+// given as URL, e.g. "https://collector.example.com/otlp/v1/logs": its path
+// replaces the default "/v1/logs", and the http scheme makes the connection
+// insecure.
 func WithHttpLogEndpoint(otelHttpEndpoint string) LoggerOption {
 	return func(t *Logger) {
+		t.endpoints = append(t.endpoints, httpLogEndpoint(otelHttpEndpoint))
+	}
+}
+
+func httpLogEndpoint(otelHttpEndpoint string) LoggerOption {
+	return func(t *Logger) {
+		endpoint, err := parseEndpoint(otelHttpEndpoint)
+		if err != nil {
+			t.errs = append(t.errs, err)
+			return
+		}
+
 		httpExporterOptions := []otlploghttp.Option{
-			otlploghttp.WithEndpoint(otelHttpEndpoint),
+			otlploghttp.WithEndpoint(endpoint.hostPort),
 		}
 
-		if t.insecure {
+		if t.insecure || endpoint.insecure {
 			httpExporterOptions = append(httpExporterOptions, otlploghttp.WithInsecure())
 		}
 
-		httpExporter, err := otlploghttp.New(context.Background(), httpExporterOptions...)
+		if endpoint.path != "" {
+			httpExporterOptions = append(httpExporterOptions, otlploghttp.WithURLPath(endpoint.path))
+		}
+
+		switch t.compression {
+		case gzipCompression:
+			httpExporterOptions = append(httpExporterOptions, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+		case noCompression:
+			httpExporterOptions = append(httpExporterOptions, otlploghttp.WithCompression(otlploghttp.NoCompression))
+		}
+
+		if t.tlsConfig != nil {
+			httpExporterOptions = append(httpExporterOptions, otlploghttp.WithTLSClientConfig(t.tlsConfig))
+		}
+
+		if len(t.headers) > 0 {
+			httpExporterOptions = append(httpExporterOptions, otlploghttp.WithHeaders(t.headers))
+		}
+
+		if t.retry != nil {
+			httpExporterOptions = append(httpExporterOptions, otlploghttp.WithRetry(otlploghttp.RetryConfig(t.retry.exporterConfig())))
+		}
+
+		if t.exportTimeout > 0 {
+			httpExporterOptions = append(httpExporterOptions, otlploghttp.WithTimeout(t.exportTimeout))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), exporterSetupTimeout)
+		defer cancel()
+
+		httpExporter, err := otlploghttp.New(ctx, httpExporterOptions...)
 		if err != nil {
-			otelzap.L().Sugar().Fatalw("Failed to create OTLP HTTP logs exporter", zap.Error(err))
+			t.errs = append(t.errs, fmt.Errorf("failed to create OTLP HTTP logs exporter: %w", err))
+			return
 		}
 
-		batcher := log.NewBatchProcessor(httpExporter,
-			log.WithMaxQueueSize(10_000),
-			log.WithExportMaxBatchSize(10_000),
-			log.WithExportInterval(10*time.Second),
-			log.WithExportTimeout(10*time.Second),
-		)
+		t.providerOptions = append(t.providerOptions, log.WithProcessor(t.newBatchProcessor(httpExporter)))
+	}
+}
 
-		t.providerOptions = append(t.providerOptions, log.WithProcessor(batcher))
+// WithLogGrpcConnectBackoff configures the backoff the gRPC log exporter uses
+// when (re-)connecting to the collector. Increasing the delays reduces the
+// reconnect pressure on a flapping collector. It has no effect on the HTTP
+// exporter.
+//
+// The default is gRPC's backoff.DefaultConfig: a base delay of 1s, a
+// multiplier of 1.6, a jitter of 0.2 and a maximum delay of 120s.
+func WithLogGrpcConnectBackoff(config backoff.Config) LoggerOption {
+	return func(t *Logger) {
+		t.grpcConnectBackoff = &config
 	}
 }
 
-func WithLogAutomaticEnv() LoggerOption {
+// WithLogRetry sets how the OTLP log exporters retry failed exports, e.g.
+// while the collector is unavailable. Setting MaxElapsedTime to 0 disables
+// retries.
+//
+// The default is DefaultRetryConfig.
+func WithLogRetry(config RetryConfig) LoggerOption {
 	return func(t *Logger) {
-		otelEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-		if otelEndpoint == "" {
-			return // if no endpoint is set, do not configure the exporter
-		}
+		t.retry = &config
+	}
+}
 
-		otelInsecure := os.Getenv("OTEL_EXPORTER_OTLP_INSECURE") == "true"
+// WithLogExportTimeout sets how long the batch processor and the OTLP
+// exporters wait for a single export of log records to complete before it is
+// cancelled. It overrides OTEL_EXPORTER_OTLP_TIMEOUT.
+//
+// The default is 10s. Independent of it, creating an exporter is bounded to
+// 10s.
+func WithLogExportTimeout(timeout time.Duration) LoggerOption {
+	return func(t *Logger) {
+		t.exportTimeout = timeout
+	}
+}
 
-		if otelInsecure {
-			WithLogInsecure()(t)
-		}
+// WithLogBatchOptions passes the given options to the batch processors of the
+// OTLP endpoints, overriding the defaults: a maximum queue size and export
+// batch size of 10,000 records, an export interval of 10s and the export
+// timeout set with WithLogExportTimeout.
+func WithLogBatchOptions(opts ...log.BatchProcessorOption) LoggerOption {
+	return func(t *Logger) {
+		t.batchOptions = append(t.batchOptions, opts...)
+	}
+}
 
-		if strings.Contains(otelEndpoint, "4317") {
-			WithGrpcLogEndpoint(otelEndpoint)(t)
-		} else if strings.Contains(otelEndpoint, "4318") {
-			WithHttpLogEndpoint(otelEndpoint)(t)
+// WithLogCompression sets the compression of the export requests, either
+// "gzip" or "none". Other values are ignored with a warning. By default,
+// export requests are not compressed.
+func WithLogCompression(compression string) LoggerOption {
+	return func(t *Logger) {
+		t.compression = compression
+	}
+}
+
+// WithLogHeaders sets headers sent with every export request, e.g. to
+// authenticate with a managed backend. They apply to both the gRPC and the
+// HTTP exporter. Headers set by multiple calls are merged, with later values
+// overriding earlier ones for the same key.
+func WithLogHeaders(headers map[string]string) LoggerOption {
+	return func(t *Logger) {
+		if t.headers == nil {
+			t.headers = make(map[string]string, len(headers))
 		}
+		for key, value := range headers {
+			t.headers[key] = value
+		}
+	}
+}
+
+// WithLogAutomaticEnv configures the OTLP endpoint from the environment: the
+// endpoint is read from OTEL_EXPORTER_OTLP_LOGS_ENDPOINT, falling back to
+// OTEL_EXPORTER_OTLP_ENDPOINT. The exporter is selected by
+// OTEL_EXPORTER_OTLP_LOGS_PROTOCOL or OTEL_EXPORTER_OTLP_PROTOCOL, "grpc" or
+// "http/protobuf", and otherwise by the port: gRPC for 4317 and HTTP for any
+// other port. The connection is insecure if
+// OTEL_EXPORTER_OTLP_INSECURE is "true". The headers listed in
+// OTEL_EXPORTER_OTLP_HEADERS are added to those set with WithLogHeaders, which
+// take precedence. Unless set with WithLogCompression,
+// OTEL_EXPORTER_OTLP_COMPRESSION selects the compression. See WithLogEnvPrefix
+// to read prefixed variables instead.
+//
+// The endpoint from the environment is added to the endpoints configured by
+// other options. If the environment sets no endpoint, no exporter is added,
+// unless WithLogDefaultLocalhostEndpoint is set.
+func WithLogAutomaticEnv() LoggerOption {
+	return func(t *Logger) {
+		t.automaticEnv = true
+	}
+}
+
+// WithLogEnvPrefix makes WithLogAutomaticEnv read the environment variables
+// prefixed with the given prefix, e.g. ACME_OTEL_EXPORTER_OTLP_ENDPOINT for
+// the prefix "ACME", falling back to the standard variables if the prefixed
+// ones are not set. By default, no prefix is used.
+func WithLogEnvPrefix(prefix string) LoggerOption {
+	return func(t *Logger) {
+		t.envPrefix = prefix
+	}
+}
+
+func (t *Logger) applyAutomaticEnv() {
+	otelEndpoint := envEndpoint(t.envPrefix, "logs")
+	if otelEndpoint == "" && !t.defaultLocalhost {
+		return // if no endpoint is set, do not configure the exporter
+	}
+
+	otelInsecure := getenv(t.envPrefix, "OTEL_EXPORTER_OTLP_INSECURE") == "true"
+
+	if otelInsecure {
+		WithLogInsecure()(t)
+	}
+
+	if t.compression == "" {
+		t.compression = getenv(t.envPrefix, "OTEL_EXPORTER_OTLP_COMPRESSION")
+	}
+
+	t.headers = mergeEnvHeaders(t.headers, getenv(t.envPrefix, "OTEL_EXPORTER_OTLP_HEADERS"))
+
+	otelEndpoint, protocol := envExporter(t.envPrefix, "logs", otelEndpoint, t.diagnostics)
+	if protocol == grpcProtocol {
+		WithGrpcLogEndpoint(otelEndpoint)(t)
+	} else {
+		WithHttpLogEndpoint(otelEndpoint)(t)
 	}
 }
 
@@ -133,8 +443,103 @@ func WithLogResources(res *resource.Resource) LoggerOption {
 	}
 }
 
+// WithoutLogDefaultDetectors leaves the host, process and OS attributes out
+// of the resource, which only describes the service and the OTel SDK then. It
+// has no effect if the resource is set with WithLogResources.
+func WithoutLogDefaultDetectors() LoggerOption {
+	return func(t *Logger) {
+		t.detectors = false
+	}
+}
+
+// WithLogResourceDetectors adds the attributes detected by the given
+// detectors, e.g. the ones for cloud environments from the OTel contrib
+// repository, to the resource, overriding detected attributes with the same
+// key. It works with both the default resource and the one set with
+// WithLogResources. If a detector fails, creating the provider fails, unless
+// only some attributes could not be detected, which is only warned about.
+func WithLogResourceDetectors(detectors ...resource.Detector) LoggerOption {
+	return func(t *Logger) {
+		t.resourceDetectors = append(t.resourceDetectors, detectors...)
+	}
+}
+
+// WithLogResourceAttributes merges the given attributes into the resource,
+// overriding attributes with the same key. Attributes with an empty key or an
+// invalid or empty value are skipped with a warning.
+func WithLogResourceAttributes(attrs ...attribute.KeyValue) LoggerOption {
+	return func(t *Logger) {
+		t.resourceAttributes = append(t.resourceAttributes, attrs...)
+	}
+}
+
+// WithLogServiceName sets the service.name resource attribute, overriding
+// OTEL_SERVICE_NAME, OTEL_RESOURCE_ATTRIBUTES and the name of the executable
+// it defaults to.
+func WithLogServiceName(name string) LoggerOption {
+	return WithLogResourceAttributes(semconv.ServiceName(name))
+}
+
+// WithLogServiceVersion sets the service.version resource attribute,
+// overriding OTEL_SERVICE_VERSION and OTEL_RESOURCE_ATTRIBUTES, e.g. to set it
+// from the build info.
+func WithLogServiceVersion(version string) LoggerOption {
+	return WithLogResourceAttributes(semconv.ServiceVersion(version))
+}
+
+// WithLogServiceInstanceID sets the service.instance.id resource attribute,
+// which distinguishes the replicas of a service. It overrides
+// OTEL_RESOURCE_ATTRIBUTES and the default: the HOSTNAME environment variable,
+// or a random UUID generated once per process if it is not set.
+func WithLogServiceInstanceID(id string) LoggerOption {
+	return WithLogResourceAttributes(semconv.ServiceInstanceID(id))
+}
+
+// WithLogStdoutFallbackOnExportFailure writes the records the OTLP exporters
+// failed to export to stderr, one OTLP-JSON encoded ExportLogsServiceRequest
+// per line, so that a log scraper can still pick them up during a collector
+// outage. At most 1000 records per minute are written to avoid flooding
+// stderr; further failed records are dropped.
+func WithLogStdoutFallbackOnExportFailure() LoggerOption {
+	return func(t *Logger) {
+		t.stdoutFallback = true
+	}
+}
+
+// WithLogFlushEveryN makes the batch processors flush after every n emitted
+// records, regardless of the export interval, for low latency exports while
+// debugging. The flush runs in the background, so logging doesn't wait for
+// the export; it is skipped if the previous one is still running, and waited
+// for on shutdown. It is disabled by default, or if n is not positive.
+func WithLogFlushEveryN(n int) LoggerOption {
+	return func(t *Logger) {
+		t.flushEvery = n
+	}
+}
+
 func WithoutRegisterLogProvider() LoggerOption {
 	return func(t *Logger) {
 		t.register = false
 	}
 }
+
+// WithLogDiagnosticLogger sets the logger the problems with the configuration
+// of the logger provider, e.g. an unsupported compression or invalid resource
+// attributes, are logged to. By default, they are logged to the global
+// otelzap.L(), which may not be set up yet while the providers are created.
+func WithLogDiagnosticLogger(logger *otelzap.Logger) LoggerOption {
+	return func(t *Logger) {
+		t.diagnostics = logger
+	}
+}
+
+// WithLogDefaultLocalhostEndpoint makes WithLogAutomaticEnv export the
+// log records to the local collector if the environment sets no endpoint: to
+// "http://localhost:4317" via gRPC, or to "http://localhost:4318" via HTTP if
+// the protocol variables select "http/protobuf". By default, no exporter is
+// added then.
+func WithLogDefaultLocalhostEndpoint(enabled bool) LoggerOption {
+	return func(t *Logger) {
+		t.defaultLocalhost = enabled
+	}
+}
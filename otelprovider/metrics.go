@@ -0,0 +1,432 @@
+package otelprovider
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"google.golang.org/grpc/credentials"
+)
+
+type Meter struct {
+	providerOptions []metric.Option
+	insecure        bool
+	resources       *resource.Resource
+	detectors       bool
+	// resourceDetectors are the additional detectors set with
+	// WithMetricResourceDetectors.
+	resourceDetectors []resource.Detector
+	register          bool
+
+	// endpoints create the exporters once all options have been applied,
+	// so that the order in which options are passed does not matter.
+	endpoints          []MeterOption
+	retry              *RetryConfig
+	resourceAttributes []attribute.KeyValue
+	automaticEnv       bool
+	envPrefix          string
+	defaultLocalhost   bool
+	headers            map[string]string
+	tlsConfig          *tls.Config
+	compression        string
+
+	// diagnostics logs the problems with the configuration, see
+	// WithMetricDiagnosticLogger.
+	diagnostics *otelzap.Logger
+
+	// errs collects the errors of creating the exporters.
+	errs []error
+}
+
+// NewMeterProvider creates a MeterProvider exporting the metrics to the
+// configured OTLP endpoints with a PeriodicReader, and registers it globally
+// unless WithoutRegisterMeterProvider is given.
+//
+// Failing to create an exporter, e.g. due to an invalid endpoint, does not keep
+// the provider from being created: the exporter is left out and the error is
+// returned along with the provider, which the application may keep using.
+// If the resource describing the service can't be created, no provider is
+// created and the error is returned.
+func NewMeterProvider(opts ...MeterOption) (*metric.MeterProvider, error) {
+	m := &Meter{
+		insecure:        false,
+		providerOptions: []metric.Option{},
+		detectors:       true,
+		register:        true,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.diagnostics == nil {
+		m.diagnostics = otelzap.L()
+	}
+
+	if m.resources == nil {
+		resources, err := cachedOtelResources(m.detectors, m.diagnostics)
+		if err != nil {
+			return nil, err
+		}
+		m.resources = resources
+	}
+
+	if len(m.resourceDetectors) > 0 {
+		resources, err := mergeDetectedResource(m.resources, m.resourceDetectors, m.diagnostics)
+		if err != nil {
+			return nil, err
+		}
+		m.resources = resources
+	}
+
+	if m.automaticEnv {
+		m.applyAutomaticEnv()
+	}
+
+	if m.tlsConfig != nil && m.insecure {
+		m.diagnostics.Warn("Both a TLS config and an insecure connection are configured for the OTLP metric exporters, using the TLS config")
+		m.insecure = false
+	}
+
+	m.compression = validCompression(m.compression, m.diagnostics)
+
+	for _, endpoint := range m.endpoints {
+		endpoint(m)
+	}
+
+	m.resources = mergeResourceAttributes(m.resources, m.resourceAttributes, m.diagnostics)
+	m.providerOptions = append(m.providerOptions, metric.WithResource(m.resources))
+	meterProvider := metric.NewMeterProvider(m.providerOptions...)
+
+	// Register the Provider globally
+	if m.register {
+		otel.SetMeterProvider(meterProvider)
+	}
+
+	return meterProvider, errors.Join(m.errs...)
+}
+
+// MeterOption applies a configuration to the given config.
+type MeterOption func(t *Meter)
+
+func WithMetricInsecure() MeterOption {
+	return func(t *Meter) {
+		t.insecure = true
+	}
+}
+
+// WithMetricTLSConfig sets the TLS configuration of the connection to the
+// collector, e.g. to trust a private CA. It takes precedence over
+// WithMetricInsecure, with a warning logged if both are set.
+func WithMetricTLSConfig(config *tls.Config) MeterOption {
+	return func(t *Meter) {
+		t.tlsConfig = config
+	}
+}
+
+// WithGrpcMetricEndpoint exports the metrics to the OTLP gRPC endpoint, e.g.
+// "localhost:4317". Endpoint options accumulate: every endpoint, including the
+// ones added by WithHttpMetricEndpoint and WithMetricAutomaticEnv, gets its own
+// exporter and periodic reader. The endpoint may also be given as URL, e.g.
+// "http://localhost:4317", with the http scheme making the connection
+// insecure.
+func WithGrpcMetricEndpoint(otelGrpcEndpoint string) MeterOption {
+	return func(t *Meter) {
+		t.endpoints = append(t.endpoints, grpcMetricEndpoint(otelGrpcEndpoint))
+	}
+}
+
+func grpcMetricEndpoint(otelGrpcEndpoint string) MeterOption {
+	return func(t *Meter) {
+		endpoint, err := parseEndpoint(otelGrpcEndpoint)
+		if err != nil {
+			t.errs = append(t.errs, err)
+			return
+		}
+
+		grpcExporterOptions := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithEndpoint(endpoint.hostPort),
+		}
+
+		if t.insecure || endpoint.insecure {
+			grpcExporterOptions = append(grpcExporterOptions, otlpmetricgrpc.WithInsecure())
+		}
+
+		if t.compression == gzipCompression {
+			grpcExporterOptions = append(grpcExporterOptions, otlpmetricgrpc.WithCompressor(gzipCompression))
+		}
+
+		if t.tlsConfig != nil {
+			grpcExporterOptions = append(grpcExporterOptions, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(t.tlsConfig)))
+		}
+
+		if len(t.headers) > 0 {
+			grpcExporterOptions = append(grpcExporterOptions, otlpmetricgrpc.WithHeaders(t.headers))
+		}
+
+		if t.retry != nil {
+			grpcExporterOptions = append(grpcExporterOptions, otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig(t.retry.exporterConfig())))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), exporterSetupTimeout)
+		defer cancel()
+
+		grpcExporter, err := otlpmetricgrpc.New(ctx, grpcExporterOptions...)
+		if err != nil {
+			t.errs = append(t.errs, fmt.Errorf("failed to create OTLP gRPC metric exporter: %w", err))
+			return
+		}
+
+		t.providerOptions = append(t.providerOptions, metric.WithReader(metric.NewPeriodicReader(grpcExporter)))
+	}
+}
+
+// WithHttpMetricEndpoint exports the metrics to the OTLP HTTP endpoint, e.g.
+// "localhost:4318". Like all endpoint options, it adds an exporter rather than
+// replacing the ones configured by other options. The endpoint may also be
+// given as URL, e.g. "https://collector.example.com/otlp/v1/metrics": its
+// path replaces the default "/v1/metrics", and the http scheme makes the
+// connection insecure.
+func WithHttpMetricEndpoint(otelHttpEndpoint string) MeterOption {
+	return func(t *Meter) {
+		t.endpoints = append(t.endpoints, httpMetricEndpoint(otelHttpEndpoint))
+	}
+}
+
+func httpMetricEndpoint(otelHttpEndpoint string) MeterOption {
+	return func(t *Meter) {
+		endpoint, err := parseEndpoint(otelHttpEndpoint)
+		if err != nil {
+			t.errs = append(t.errs, err)
+			return
+		}
+
+		httpExporterOptions := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithEndpoint(endpoint.hostPort),
+		}
+
+		if t.insecure || endpoint.insecure {
+			httpExporterOptions = append(httpExporterOptions, otlpmetrichttp.WithInsecure())
+		}
+
+		if endpoint.path != "" {
+			httpExporterOptions = append(httpExporterOptions, otlpmetrichttp.WithURLPath(endpoint.path))
+		}
+
+		switch t.compression {
+		case gzipCompression:
+			httpExporterOptions = append(httpExporterOptions, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+		case noCompression:
+			httpExporterOptions = append(httpExporterOptions, otlpmetrichttp.WithCompression(otlpmetrichttp.NoCompression))
+		}
+
+		if t.tlsConfig != nil {
+			httpExporterOptions = append(httpExporterOptions, otlpmetrichttp.WithTLSClientConfig(t.tlsConfig))
+		}
+
+		if len(t.headers) > 0 {
+			httpExporterOptions = append(httpExporterOptions, otlpmetrichttp.WithHeaders(t.headers))
+		}
+
+		if t.retry != nil {
+			httpExporterOptions = append(httpExporterOptions, otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig(t.retry.exporterConfig())))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), exporterSetupTimeout)
+		defer cancel()
+
+		httpExporter, err := otlpmetrichttp.New(ctx, httpExporterOptions...)
+		if err != nil {
+			t.errs = append(t.errs, fmt.Errorf("failed to create OTLP HTTP metric exporter: %w", err))
+			return
+		}
+
+		t.providerOptions = append(t.providerOptions, metric.WithReader(metric.NewPeriodicReader(httpExporter)))
+	}
+}
+
+// WithMetricRetry sets how the OTLP metric exporters retry failed exports,
+// e.g. while the collector is unavailable. Setting MaxElapsedTime to 0
+// disables retries.
+//
+// The default is DefaultRetryConfig.
+func WithMetricRetry(config RetryConfig) MeterOption {
+	return func(t *Meter) {
+		t.retry = &config
+	}
+}
+
+// WithMetricCompression sets the compression of the export requests, either
+// "gzip" or "none". Other values are ignored with a warning. By default,
+// export requests are not compressed.
+func WithMetricCompression(compression string) MeterOption {
+	return func(t *Meter) {
+		t.compression = compression
+	}
+}
+
+// WithMetricHeaders sets headers sent with every export request, e.g. to
+// authenticate with a managed backend. They apply to both the gRPC and the
+// HTTP exporter. Headers set by multiple calls are merged, with later values
+// overriding earlier ones for the same key.
+func WithMetricHeaders(headers map[string]string) MeterOption {
+	return func(t *Meter) {
+		if t.headers == nil {
+			t.headers = make(map[string]string, len(headers))
+		}
+		for key, value := range headers {
+			t.headers[key] = value
+		}
+	}
+}
+
+// WithMetricAutomaticEnv configures the OTLP endpoint from the environment: the
+// endpoint is read from OTEL_EXPORTER_OTLP_METRICS_ENDPOINT, falling back to
+// OTEL_EXPORTER_OTLP_ENDPOINT. The exporter is selected by
+// OTEL_EXPORTER_OTLP_METRICS_PROTOCOL or OTEL_EXPORTER_OTLP_PROTOCOL, "grpc" or
+// "http/protobuf", and otherwise by the port: gRPC for 4317 and HTTP for any
+// other port. The connection is insecure if
+// OTEL_EXPORTER_OTLP_INSECURE is "true". The headers listed in
+// OTEL_EXPORTER_OTLP_HEADERS are added to those set with WithMetricHeaders,
+// which take precedence. Unless set with WithMetricCompression,
+// OTEL_EXPORTER_OTLP_COMPRESSION selects the compression. See
+// WithMetricEnvPrefix to read prefixed variables instead.
+//
+// If the environment sets no endpoint, no exporter is added, unless
+// WithMetricDefaultLocalhostEndpoint is set.
+func WithMetricAutomaticEnv() MeterOption {
+	return func(t *Meter) {
+		t.automaticEnv = true
+	}
+}
+
+// WithMetricEnvPrefix makes WithMetricAutomaticEnv read the environment
+// variables prefixed with the given prefix, e.g.
+// ACME_OTEL_EXPORTER_OTLP_ENDPOINT for the prefix "ACME", falling back to the
+// standard variables if the prefixed ones are not set. By default, no prefix
+// is used.
+func WithMetricEnvPrefix(prefix string) MeterOption {
+	return func(t *Meter) {
+		t.envPrefix = prefix
+	}
+}
+
+func (t *Meter) applyAutomaticEnv() {
+	otelEndpoint := envEndpoint(t.envPrefix, "metrics")
+	if otelEndpoint == "" && !t.defaultLocalhost {
+		return // if no endpoint is set, do not configure the exporter
+	}
+
+	otelInsecure := getenv(t.envPrefix, "OTEL_EXPORTER_OTLP_INSECURE") == "true"
+
+	if otelInsecure {
+		WithMetricInsecure()(t)
+	}
+
+	if t.compression == "" {
+		t.compression = getenv(t.envPrefix, "OTEL_EXPORTER_OTLP_COMPRESSION")
+	}
+
+	t.headers = mergeEnvHeaders(t.headers, getenv(t.envPrefix, "OTEL_EXPORTER_OTLP_HEADERS"))
+
+	otelEndpoint, protocol := envExporter(t.envPrefix, "metrics", otelEndpoint, t.diagnostics)
+	if protocol == grpcProtocol {
+		WithGrpcMetricEndpoint(otelEndpoint)(t)
+	} else {
+		WithHttpMetricEndpoint(otelEndpoint)(t)
+	}
+}
+
+func WithMetricResources(res *resource.Resource) MeterOption {
+	return func(t *Meter) {
+		t.resources = res
+	}
+}
+
+// WithoutMetricDefaultDetectors leaves the host, process and OS attributes out
+// of the resource, which only describes the service and the OTel SDK then. It
+// has no effect if the resource is set with WithMetricResources.
+func WithoutMetricDefaultDetectors() MeterOption {
+	return func(t *Meter) {
+		t.detectors = false
+	}
+}
+
+// WithMetricResourceDetectors adds the attributes detected by the given
+// detectors, e.g. the ones for cloud environments from the OTel contrib
+// repository, to the resource, overriding detected attributes with the same
+// key. It works with both the default resource and the one set with
+// WithMetricResources. If a detector fails, creating the provider fails, unless
+// only some attributes could not be detected, which is only warned about.
+func WithMetricResourceDetectors(detectors ...resource.Detector) MeterOption {
+	return func(t *Meter) {
+		t.resourceDetectors = append(t.resourceDetectors, detectors...)
+	}
+}
+
+// WithMetricResourceAttributes merges the given attributes into the resource,
+// overriding attributes with the same key. Attributes with an empty key or an
+// invalid or empty value are skipped with a warning.
+func WithMetricResourceAttributes(attrs ...attribute.KeyValue) MeterOption {
+	return func(t *Meter) {
+		t.resourceAttributes = append(t.resourceAttributes, attrs...)
+	}
+}
+
+// WithMetricServiceName sets the service.name resource attribute, overriding
+// OTEL_SERVICE_NAME, OTEL_RESOURCE_ATTRIBUTES and the name of the executable
+// it defaults to.
+func WithMetricServiceName(name string) MeterOption {
+	return WithMetricResourceAttributes(semconv.ServiceName(name))
+}
+
+// WithMetricServiceVersion sets the service.version resource attribute,
+// overriding OTEL_SERVICE_VERSION and OTEL_RESOURCE_ATTRIBUTES, e.g. to set it
+// from the build info.
+func WithMetricServiceVersion(version string) MeterOption {
+	return WithMetricResourceAttributes(semconv.ServiceVersion(version))
+}
+
+// WithMetricServiceInstanceID sets the service.instance.id resource attribute,
+// which distinguishes the replicas of a service. It overrides
+// OTEL_RESOURCE_ATTRIBUTES and the default: the HOSTNAME environment variable,
+// or a random UUID generated once per process if it is not set.
+func WithMetricServiceInstanceID(id string) MeterOption {
+	return WithMetricResourceAttributes(semconv.ServiceInstanceID(id))
+}
+
+func WithoutRegisterMeterProvider() MeterOption {
+	return func(t *Meter) {
+		t.register = false
+	}
+}
+
+// WithMetricDiagnosticLogger sets the logger the problems with the configuration
+// of the meter provider, e.g. an unsupported compression or invalid resource
+// attributes, are logged to. By default, they are logged to the global
+// otelzap.L(), which may not be set up yet while the providers are created.
+func WithMetricDiagnosticLogger(logger *otelzap.Logger) MeterOption {
+	return func(t *Meter) {
+		t.diagnostics = logger
+	}
+}
+
+// WithMetricDefaultLocalhostEndpoint makes WithMetricAutomaticEnv export the
+// metrics to the local collector if the environment sets no endpoint: to
+// "http://localhost:4317" via gRPC, or to "http://localhost:4318" via HTTP if
+// the protocol variables select "http/protobuf". By default, no exporter is
+// added then.
+func WithMetricDefaultLocalhostEndpoint(enabled bool) MeterOption {
+	return func(t *Meter) {
+		t.defaultLocalhost = enabled
+	}
+}
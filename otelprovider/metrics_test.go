@@ -0,0 +1,48 @@
+package otelprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeterProviderConnectionOptions(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requests <- r:
+		default:
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(collector.Close)
+
+	t.Setenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", collector.URL+"/v1/metrics")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=from-env,tenant=acme")
+	t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "gzip")
+
+	provider, err := NewMeterProvider(
+		WithMetricAutomaticEnv(),
+		WithMetricHeaders(map[string]string{"api-key": "secret"}),
+		WithMetricRetry(RetryConfig{}),
+		WithoutMetricDefaultDetectors(),
+		WithoutRegisterMeterProvider(),
+	)
+	require.NoError(t, err)
+
+	counter, err := provider.Meter("test").Int64Counter("requests")
+	require.NoError(t, err)
+	counter.Add(context.Background(), 1)
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	require.Len(t, requests, 1)
+	request := <-requests
+	assert.Equal(t, "/v1/metrics", request.URL.Path)
+	assert.Equal(t, "secret", request.Header.Get("api-key"))
+	assert.Equal(t, "acme", request.Header.Get("tenant"))
+	assert.Equal(t, "gzip", request.Header.Get("Content-Encoding"))
+}
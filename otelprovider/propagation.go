@@ -0,0 +1,132 @@
+package otelprovider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/zap"
+)
+
+type Propagators struct {
+	formats   []string
+	envPrefix string
+
+	// diagnostics logs the problems with the configuration, see
+	// WithPropagatorDiagnosticLogger.
+	diagnostics *otelzap.Logger
+}
+
+// PropagatorOption applies a configuration to the given config.
+type PropagatorOption func(t *Propagators)
+
+// ConfigurePropagators registers the composite of the configured propagators
+// as the global TextMapPropagator and returns it.
+//
+// The formats are read from OTEL_PROPAGATORS, a comma-separated list of
+// "tracecontext", "baggage", "b3", "b3multi", "jaeger" or "none", and default
+// to W3C TraceContext and Baggage if it is not set. Formats added with
+// WithB3Propagator or WithJaegerPropagator are used in addition.
+func ConfigurePropagators(opts ...PropagatorOption) propagation.TextMapPropagator {
+	p := &Propagators{}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.diagnostics == nil {
+		p.diagnostics = otelzap.L()
+	}
+
+	formats := []string{"tracecontext", "baggage"}
+	if env := getenv(p.envPrefix, "OTEL_PROPAGATORS"); env != "" {
+		formats = strings.Split(env, ",")
+	}
+	formats = append(formats, p.formats...)
+
+	var propagators []propagation.TextMapPropagator
+	seen := make(map[string]bool, len(formats))
+	for _, format := range formats {
+		format = strings.TrimSpace(format)
+		if seen[format] {
+			continue
+		}
+		seen[format] = true
+
+		switch format {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "b3":
+			propagators = append(propagators, b3.New())
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "jaeger":
+			propagators = append(propagators, jaeger.Jaeger{})
+		case "none", "":
+		default:
+			p.diagnostics.Warn("Unsupported propagator, skipping it", zap.String("propagator", format))
+		}
+	}
+
+	propagator := propagation.NewCompositeTextMapPropagator(propagators...)
+	otel.SetTextMapPropagator(propagator)
+	return propagator
+}
+
+// WithB3Propagator adds the B3 single header propagator.
+func WithB3Propagator() PropagatorOption {
+	return func(t *Propagators) {
+		t.formats = append(t.formats, "b3")
+	}
+}
+
+// WithB3MultiPropagator adds the B3 multiple header propagator.
+func WithB3MultiPropagator() PropagatorOption {
+	return func(t *Propagators) {
+		t.formats = append(t.formats, "b3multi")
+	}
+}
+
+// WithJaegerPropagator adds the Jaeger (uber-trace-id) propagator.
+func WithJaegerPropagator() PropagatorOption {
+	return func(t *Propagators) {
+		t.formats = append(t.formats, "jaeger")
+	}
+}
+
+// WithPropagatorEnvPrefix makes ConfigurePropagators read OTEL_PROPAGATORS
+// prefixed with the given prefix, falling back to the standard variable if
+// the prefixed one is not set.
+func WithPropagatorEnvPrefix(prefix string) PropagatorOption {
+	return func(t *Propagators) {
+		t.envPrefix = prefix
+	}
+}
+
+// WithPropagatorDiagnosticLogger sets the logger unsupported formats in
+// OTEL_PROPAGATORS are logged to. By default, they are logged to the global
+// otelzap.L(), which may not be set up yet while the propagators are
+// configured.
+func WithPropagatorDiagnosticLogger(logger *otelzap.Logger) PropagatorOption {
+	return func(t *Propagators) {
+		t.diagnostics = logger
+	}
+}
+
+// ContextFromCarrier extracts the trace context (e.g. a W3C traceparent) from
+// the carrier using the globally configured propagators and returns a copy of
+// ctx carrying it. This is meant for entry points without HTTP or gRPC
+// middleware, like message queue consumers or cron triggers, so that logging
+// with the returned context is correlated with the upstream trace.
+//
+// Keys are looked up as-is, so they have to match the header names used by
+// the propagators, e.g. "traceparent".
+func ContextFromCarrier(ctx context.Context, carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
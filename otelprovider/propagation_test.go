@@ -0,0 +1,28 @@
+package otelprovider
+
+import (
+	"testing"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestConfigurePropagatorsDiagnosticLogger(t *testing.T) {
+	t.Setenv("OTEL_PROPAGATORS", "tracecontext,xray,b3")
+	previous := otel.GetTextMapPropagator()
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	propagator := ConfigurePropagators(WithPropagatorDiagnosticLogger(otelzap.New(zap.New(core))))
+
+	assert.Subset(t, propagator.Fields(), []string{"traceparent", "tracestate", "x-b3-traceid"})
+
+	warnings := logs.FilterLevelExact(zapcore.WarnLevel).All()
+	if assert.Len(t, warnings, 1) {
+		assert.Equal(t, "xray", warnings[0].ContextMap()["propagator"])
+	}
+}
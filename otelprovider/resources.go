@@ -1,32 +1,236 @@
 package otelprovider
 
 import (
-	"go.opentelemetry.io/otel/sdk/resource"
-	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.uber.org/zap"
+)
+
+// DefaultResource returns the resource NewLogger, NewTracer and
+// NewMeterProvider use by default: the service name, version and instance id,
+// the attributes from OTEL_RESOURCE_ATTRIBUTES, and the detected SDK, host,
+// process and OS attributes. It is built once and cached, so passing it to all
+// providers with WithLogResources, WithTraceResources and WithMetricResources
+// guarantees the same resource across all signals, even if the environment
+// changes in between.
+func DefaultResource() (*resource.Resource, error) {
+	return cachedOtelResources(true, otelzap.L())
+}
+
+// resourceCache holds a resource built once per process.
+type resourceCache struct {
+	once sync.Once
+	res  *resource.Resource
+	err  error
+}
+
+var (
+	defaultResource                 resourceCache
+	defaultResourceWithoutDetectors resourceCache
 )
 
-func newOtelResources() *resource.Resource {
-	serviceName := os.Getenv("OTEL_SERVICE_NAME")
-	if serviceName == "" {
-		serviceName = filepath.Base(os.Args[0])
+// cachedOtelResources returns the resource built by newOtelResources, which is
+// only built once per process. Warnings about building it are logged to the
+// logger of the first call.
+func cachedOtelResources(detectors bool, logger *otelzap.Logger) (*resource.Resource, error) {
+	cache := &defaultResourceWithoutDetectors
+	if detectors {
+		cache = &defaultResource
+	}
+
+	cache.once.Do(func() {
+		cache.res, cache.err = newOtelResources(detectors, logger)
+	})
+	return cache.res, cache.err
+}
+
+// newOtelResources returns the default resource, describing the OTel SDK,
+// merged with the attributes set in OTEL_RESOURCE_ATTRIBUTES and the service
+// name, version and instance id. With detectors, the host, process and OS
+// attributes are detected and added as well, unless set in
+// OTEL_RESOURCE_ATTRIBUTES.
+func newOtelResources(detectors bool, logger *otelzap.Logger) (*resource.Resource, error) {
+	res := resource.Default()
+
+	if detectors {
+		detected, err := resource.New(context.Background(),
+			resource.WithHost(),
+			// Like resource.WithProcess, but without the command line
+			// arguments, which may contain secrets.
+			resource.WithProcessPID(),
+			resource.WithProcessExecutableName(),
+			resource.WithProcessExecutablePath(),
+			resource.WithProcessOwner(),
+			resource.WithProcessRuntimeName(),
+			resource.WithProcessRuntimeVersion(),
+			resource.WithProcessRuntimeDescription(),
+			resource.WithOS(),
+		)
+		if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+			return nil, fmt.Errorf("failed to detect OTel resource: %w", err)
+		}
+		if err != nil {
+			// Some attributes could not be detected, e.g. the owner of the
+			// process in a container, the others are still worth keeping.
+			logger.Warn("Failed to detect some resource attributes", zap.Error(err))
+		}
+
+		res, err = resource.Merge(res, detected)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTel resource: %w", err)
+		}
+	}
+
+	// OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME take precedence over
+	// the detected attributes.
+	fromEnv, err := resource.New(context.Background(), resource.WithFromEnv())
+	if err != nil {
+		if !errors.Is(err, resource.ErrPartialResource) {
+			return nil, fmt.Errorf("failed to read OTel resource from the environment: %w", err)
+		}
+		logger.Warn("Failed to parse some resource attributes from the environment", zap.Error(err))
+	}
+
+	res, err = resource.Merge(res, fromEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTel resource: %w", err)
+	}
+
+	serviceName, ok := fromEnv.Set().Value(semconv.ServiceNameKey)
+	if !ok {
+		serviceName = attribute.StringValue(filepath.Base(os.Args[0]))
+	}
+
+	serviceVersion, ok := fromEnv.Set().Value(semconv.ServiceVersionKey)
+	if env := os.Getenv("OTEL_SERVICE_VERSION"); env != "" {
+		serviceVersion = attribute.StringValue(env)
+	} else if !ok {
+		serviceVersion = attribute.StringValue("0.0.0-unset")
 	}
 
-	serviceVersion := os.Getenv("OTEL_SERVICE_VERSION")
-	if serviceVersion == "" {
-		serviceVersion = "0.0.0-unset"
+	serviceInstanceID, ok := fromEnv.Set().Value(semconv.ServiceInstanceIDKey)
+	if !ok {
+		serviceInstanceID = attribute.StringValue(defaultServiceInstanceID())
 	}
 
-	res, err := resource.Merge(resource.Default(),
-		resource.NewWithAttributes(semconv.SchemaURL,
-			semconv.ServiceName(serviceName),
-			semconv.ServiceVersion(serviceVersion),
-		))
+	res, err = resource.Merge(res,
+		resource.NewWithAttributes(semconv.SchemaURL, validateAttributes([]attribute.KeyValue{
+			semconv.ServiceNameKey.String(serviceName.Emit()),
+			semconv.ServiceVersionKey.String(serviceVersion.Emit()),
+			semconv.ServiceInstanceIDKey.String(serviceInstanceID.Emit()),
+		}, logger)...))
 
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to create OTel resource: %w", err)
+	}
+
+	return res, nil
+}
+
+// mergeDetectedResource merges the attributes detected by the detectors into
+// res, overriding attributes with the same key. If the schema URLs of the
+// resources conflict, the detected attributes are merged without schema URL.
+func mergeDetectedResource(
+	res *resource.Resource, detectors []resource.Detector, logger *otelzap.Logger,
+) (*resource.Resource, error) {
+	detected, err := resource.New(context.Background(), resource.WithDetectors(detectors...))
+	if err != nil {
+		if !errors.Is(err, resource.ErrPartialResource) {
+			return nil, fmt.Errorf("failed to detect OTel resource: %w", err)
+		}
+		logger.Warn("Failed to detect some resource attributes", zap.Error(err))
+	}
+
+	merged, err := resource.Merge(res, detected)
+	if errors.Is(err, resource.ErrSchemaURLConflict) {
+		merged, err = resource.Merge(res, resource.NewSchemaless(detected.Attributes()...))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTel resource: %w", err)
+	}
+
+	return merged, nil
+}
+
+// defaultServiceInstanceID returns the HOSTNAME, which is the pod name in
+// Kubernetes, or else a random UUID. It is the same for all providers of the
+// process.
+var defaultServiceInstanceID = sync.OnceValue(func() string {
+	if hostname := os.Getenv("HOSTNAME"); hostname != "" {
+		return hostname
+	}
+
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[:])
+	uuid[6] = uuid[6]&0x0f | 0x40 // version 4
+	uuid[8] = uuid[8]&0x3f | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+})
+
+// mergeResourceAttributes merges the valid attributes into res, overriding
+// attributes with the same key.
+func mergeResourceAttributes(
+	res *resource.Resource, attrs []attribute.KeyValue, logger *otelzap.Logger,
+) *resource.Resource {
+	attrs = validateAttributes(attrs, logger)
+	if len(attrs) == 0 {
+		return res
+	}
+
+	// A schemaless resource never conflicts with the schema URL of res.
+	merged, _ := resource.Merge(res, resource.NewSchemaless(attrs...))
+	return merged
+}
+
+// validateAttributes warns about and skips attributes with an empty key or an
+// invalid value. Duplicate keys with conflicting values are warned about as
+// well; like in a resource, the last one wins.
+func validateAttributes(attrs []attribute.KeyValue, logger *otelzap.Logger) []attribute.KeyValue {
+	valid := make([]attribute.KeyValue, 0, len(attrs))
+	index := make(map[attribute.Key]int, len(attrs))
+
+	for _, attr := range attrs {
+		switch {
+		case strings.TrimSpace(string(attr.Key)) == "":
+			logger.Warn("Skipping resource attribute with empty key", zap.String("value", attr.Value.Emit()))
+			continue
+
+		case attr.Value.Type() == attribute.INVALID:
+			logger.Warn("Skipping resource attribute with invalid value", zap.String("key", string(attr.Key)))
+			continue
+
+		case attr.Value.Type() == attribute.STRING && strings.TrimSpace(attr.Value.AsString()) == "":
+			logger.Warn("Skipping resource attribute with empty value", zap.String("key", string(attr.Key)))
+			continue
+		}
+
+		if i, ok := index[attr.Key]; ok {
+			if valid[i].Value.Emit() != attr.Value.Emit() {
+				logger.Warn("Conflicting values for resource attribute, using the last one",
+					zap.String("key", string(attr.Key)),
+					zap.String("previous", valid[i].Value.Emit()),
+					zap.String("value", attr.Value.Emit()),
+				)
+			}
+			valid[i] = attr
+			continue
+		}
+
+		index[attr.Key] = len(valid)
+		valid = append(valid, attr)
 	}
 
-	return res
+	return valid
 }
@@ -0,0 +1,45 @@
+package otelprovider
+
+import (
+	"time"
+)
+
+// RetryConfig configures how the OTLP exporters retry failed exports, with an
+// exponential backoff between the initial and the maximum interval.
+type RetryConfig struct {
+	// InitialInterval is the time to wait after the first failure before
+	// retrying.
+	InitialInterval time.Duration
+	// MaxInterval is the upper bound of the backoff interval.
+	MaxInterval time.Duration
+	// MaxElapsedTime is the maximum time spent trying to export a batch,
+	// after which it is dropped. Zero disables retries.
+	MaxElapsedTime time.Duration
+}
+
+// DefaultRetryConfig is the retry policy of the OTLP exporters if none is set:
+// retrying after 5s, backing off up to 30s between attempts, for at most a
+// minute.
+var DefaultRetryConfig = RetryConfig{
+	InitialInterval: 5 * time.Second,
+	MaxInterval:     30 * time.Second,
+	MaxElapsedTime:  time.Minute,
+}
+
+// retryConfig has the layout of the RetryConfig types of the OTLP exporters,
+// so that it can be converted to any of them.
+type retryConfig struct {
+	Enabled         bool
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+}
+
+func (c RetryConfig) exporterConfig() retryConfig {
+	return retryConfig{
+		Enabled:         c.MaxElapsedTime > 0,
+		InitialInterval: c.InitialInterval,
+		MaxInterval:     c.MaxInterval,
+		MaxElapsedTime:  c.MaxElapsedTime,
+	}
+}
@@ -0,0 +1,261 @@
+package otelprovider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// maxBufferedSpansPerTrace bounds the number of sampled-out spans buffered for
+// a single trace until it is known whether the trace contains an error.
+const maxBufferedSpansPerTrace = 1000
+
+// WithTraceRatioSamplerKeepErrors samples the given ratio of traces, like
+// trace.ParentBased(trace.TraceIDRatioBased(ratio)), but still keeps the
+// sampled-out traces in which an error is recorded.
+//
+// Since the head sampler can't know whether an error will occur, sampled-out
+// spans are recorded rather than dropped, and buffered until the local root
+// span of their trace ends. If any span of the trace ends with an error status,
+// e.g. because an error was logged with otelzap within it, the buffered spans
+// of the trace are exported as if they had been sampled, as well as all of
+// its spans ending later on. Otherwise, the buffered spans are dropped once
+// the local root span ends. Spans ending after their local root span, e.g. of
+// asynchronous work, are only exported if they have an error status
+// themselves.
+//
+// Keep in mind that recording all spans costs more than dropping them, and
+// that spans of the trace that ended in other processes are not kept.
+func WithTraceRatioSamplerKeepErrors(ratio float64) TracerOption {
+	return func(t *Tracer) {
+		t.sampler = trace.ParentBased(trace.TraceIDRatioBased(ratio))
+		t.keepErrors = true
+	}
+}
+
+// WithTraceSampler sets the sampler of the TracerProvider.
+//
+// By default, the sampler configured by OTEL_TRACES_SAMPLER and
+// OTEL_TRACES_SAMPLER_ARG is used, or trace.ParentBased(trace.AlwaysSample())
+// if they are not set.
+func WithTraceSampler(sampler trace.Sampler) TracerOption {
+	return func(t *Tracer) {
+		t.sampler = sampler
+		t.keepErrors = false
+	}
+}
+
+// WithTraceRatioSampler samples the given fraction of traces, following the
+// sampling decision of the parent span if there is one. It is a shorthand for
+// WithTraceSampler(trace.ParentBased(trace.TraceIDRatioBased(fraction))).
+func WithTraceRatioSampler(fraction float64) TracerOption {
+	return WithTraceSampler(trace.ParentBased(trace.TraceIDRatioBased(fraction)))
+}
+
+// traceSampler returns the sampler to configure the TracerProvider with, or
+// nil to leave it to the SDK.
+func (t *Tracer) traceSampler() trace.Sampler {
+	if !t.keepErrors {
+		return t.sampler
+	}
+	return keepErrorsSampler{sampler: t.sampler}
+}
+
+// samplerFromEnv returns the sampler configured by OTEL_TRACES_SAMPLER and
+// OTEL_TRACES_SAMPLER_ARG as specified by OpenTelemetry, or nil if it is not
+// set or not supported.
+func samplerFromEnv(prefix string, logger *otelzap.Logger) trace.Sampler {
+	name := getenv(prefix, "OTEL_TRACES_SAMPLER")
+	if name == "" {
+		return nil
+	}
+
+	ratio := func() float64 {
+		arg := getenv(prefix, "OTEL_TRACES_SAMPLER_ARG")
+		if arg == "" {
+			return 1
+		}
+
+		ratio, err := strconv.ParseFloat(arg, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			logger.Warn("Invalid OTEL_TRACES_SAMPLER_ARG, sampling all traces", zap.String("arg", arg))
+			return 1
+		}
+		return ratio
+	}
+
+	switch name {
+	case "always_on":
+		return trace.AlwaysSample()
+	case "always_off":
+		return trace.NeverSample()
+	case "traceidratio":
+		return trace.TraceIDRatioBased(ratio())
+	case "parentbased_always_on":
+		return trace.ParentBased(trace.AlwaysSample())
+	case "parentbased_always_off":
+		return trace.ParentBased(trace.NeverSample())
+	case "parentbased_traceidratio":
+		return trace.ParentBased(trace.TraceIDRatioBased(ratio()))
+	default:
+		logger.Warn("Unsupported OTEL_TRACES_SAMPLER, using the default sampler", zap.String("sampler", name))
+		return nil
+	}
+}
+
+// exportingSpanProcessor wraps the span processor exporting to an endpoint,
+// so that it also exports the sampled-out traces with errors if enabled by
+// WithTraceRatioSamplerKeepErrors.
+func (t *Tracer) exportingSpanProcessor(processor trace.SpanProcessor) trace.SpanProcessor {
+	if !t.keepErrors {
+		return processor
+	}
+	return newKeepErrorsSpanProcessor(processor)
+}
+
+// keepErrorsSampler samples like the wrapped sampler, but records the spans it
+// would drop, so that they can still be exported if their trace has an error.
+type keepErrorsSampler struct {
+	sampler trace.Sampler
+}
+
+func (s keepErrorsSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	result := s.sampler.ShouldSample(p)
+	if result.Decision == trace.Drop {
+		result.Decision = trace.RecordOnly
+	}
+	return result
+}
+
+func (s keepErrorsSampler) Description() string {
+	return fmt.Sprintf("KeepErrors{%s}", s.sampler.Description())
+}
+
+// keepErrorsSpanProcessor forwards sampled spans to the wrapped processor, and
+// buffers the sampled-out spans of a trace until either one of them has an
+// error, in which case they are forwarded as sampled spans, or the local root
+// spans of the trace ended, in which case they are dropped.
+type keepErrorsSpanProcessor struct {
+	next trace.SpanProcessor
+
+	mu     sync.Mutex
+	traces map[oteltrace.TraceID]*sampledOutTrace
+}
+
+type sampledOutTrace struct {
+	// roots is the number of local root spans of the trace still running.
+	roots int
+	keep  bool
+	spans []trace.ReadOnlySpan
+}
+
+func newKeepErrorsSpanProcessor(next trace.SpanProcessor) *keepErrorsSpanProcessor {
+	return &keepErrorsSpanProcessor{
+		next:   next,
+		traces: make(map[oteltrace.TraceID]*sampledOutTrace),
+	}
+}
+
+func (p *keepErrorsSpanProcessor) OnStart(parent context.Context, s trace.ReadWriteSpan) {
+	if sc := s.SpanContext(); !sc.IsSampled() && isLocalRoot(s) {
+		p.mu.Lock()
+		p.trace(sc.TraceID()).roots++
+		p.mu.Unlock()
+	}
+
+	p.next.OnStart(parent, s)
+}
+
+func (p *keepErrorsSpanProcessor) OnEnd(s trace.ReadOnlySpan) {
+	sc := s.SpanContext()
+	if sc.IsSampled() {
+		p.next.OnEnd(s)
+		return
+	}
+
+	var release []trace.ReadOnlySpan
+
+	p.mu.Lock()
+	tr, ok := p.traces[sc.TraceID()]
+	if !ok {
+		// The local roots of the trace already ended, e.g. for a span of
+		// asynchronous work, so there is nothing left to buffer it with.
+		p.mu.Unlock()
+		if s.Status().Code == codes.Error {
+			p.next.OnEnd(sampledSpan{ReadOnlySpan: s})
+		}
+		return
+	}
+
+	if !tr.keep && s.Status().Code == codes.Error {
+		tr.keep = true
+		release, tr.spans = tr.spans, nil
+	}
+
+	if tr.keep {
+		release = append(release, s)
+	} else if len(tr.spans) < maxBufferedSpansPerTrace {
+		tr.spans = append(tr.spans, s)
+	}
+
+	if isLocalRoot(s) {
+		tr.roots--
+	}
+	if tr.roots <= 0 {
+		delete(p.traces, sc.TraceID())
+	}
+	p.mu.Unlock()
+
+	for _, span := range release {
+		p.next.OnEnd(sampledSpan{ReadOnlySpan: span})
+	}
+}
+
+// trace returns the state of the sampled-out trace, creating it if needed.
+// It must be called with p.mu held, and only when a local root span starts, so
+// that the state is deleted again once the local roots ended.
+func (p *keepErrorsSpanProcessor) trace(id oteltrace.TraceID) *sampledOutTrace {
+	tr, ok := p.traces[id]
+	if !ok {
+		tr = &sampledOutTrace{}
+		p.traces[id] = tr
+	}
+	return tr
+}
+
+// Shutdown drops the spans of unfinished traces and shuts down the wrapped
+// processor.
+func (p *keepErrorsSpanProcessor) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.traces = make(map[oteltrace.TraceID]*sampledOutTrace)
+	p.mu.Unlock()
+
+	return p.next.Shutdown(ctx)
+}
+
+func (p *keepErrorsSpanProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+func isLocalRoot(s trace.ReadOnlySpan) bool {
+	parent := s.Parent()
+	return !parent.IsValid() || parent.IsRemote()
+}
+
+// sampledSpan reports a sampled-out span as sampled, so that exporting span
+// processors don't skip it.
+type sampledSpan struct {
+	trace.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() oteltrace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
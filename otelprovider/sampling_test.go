@@ -0,0 +1,173 @@
+package otelprovider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newKeepErrorsProvider(t *testing.T) (*sdktrace.TracerProvider, *keepErrorsSpanProcessor, *tracetest.SpanRecorder) {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	processor := newKeepErrorsSpanProcessor(recorder)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(keepErrorsSampler{sampler: sdktrace.NeverSample()}),
+		sdktrace.WithSpanProcessor(processor),
+	)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	return provider, processor, recorder
+}
+
+func bufferedTraces(p *keepErrorsSpanProcessor) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.traces)
+}
+
+func TestKeepErrorsDropsTraceWithoutError(t *testing.T) {
+	provider, processor, recorder := newKeepErrorsProvider(t)
+	tracer := provider.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	root.End()
+
+	assert.Empty(t, recorder.Ended())
+	assert.Zero(t, bufferedTraces(processor))
+}
+
+func TestKeepErrorsKeepsTraceWithError(t *testing.T) {
+	provider, processor, recorder := newKeepErrorsProvider(t)
+	tracer := provider.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, first := tracer.Start(ctx, "first")
+	first.End()
+	_, failing := tracer.Start(ctx, "failing")
+	failing.SetStatus(codes.Error, "failed")
+	failing.End()
+	root.End()
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 3)
+	for _, span := range ended {
+		assert.True(t, span.SpanContext().IsSampled(), span.Name())
+	}
+	assert.Zero(t, bufferedTraces(processor))
+}
+
+func TestKeepErrorsChildEndingAfterRoot(t *testing.T) {
+	provider, processor, recorder := newKeepErrorsProvider(t)
+	tracer := provider.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, async := tracer.Start(ctx, "async")
+	_, failing := tracer.Start(ctx, "failing")
+	root.End()
+	require.Zero(t, bufferedTraces(processor))
+
+	async.End()
+	assert.Zero(t, bufferedTraces(processor))
+	assert.Empty(t, recorder.Ended())
+
+	failing.SetStatus(codes.Error, "failed")
+	failing.End()
+	assert.Zero(t, bufferedTraces(processor))
+	require.Len(t, recorder.Ended(), 1)
+	assert.Equal(t, "failing", recorder.Ended()[0].Name())
+}
+
+func TestSamplerFromEnv(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		sampler string
+		arg     string
+		prefix  string
+		want    sdktrace.Sampler
+		warned  bool
+	}{
+		{name: "not set", want: nil},
+		{name: "always_on", sampler: "always_on", want: sdktrace.AlwaysSample()},
+		{name: "always_off", sampler: "always_off", want: sdktrace.NeverSample()},
+		{name: "traceidratio", sampler: "traceidratio", arg: "0.25", want: sdktrace.TraceIDRatioBased(0.25)},
+		{name: "traceidratio without arg", sampler: "traceidratio", want: sdktrace.TraceIDRatioBased(1)},
+		{name: "traceidratio zero", sampler: "traceidratio", arg: "0", want: sdktrace.TraceIDRatioBased(0)},
+		{
+			name:    "parentbased_always_on",
+			sampler: "parentbased_always_on",
+			want:    sdktrace.ParentBased(sdktrace.AlwaysSample()),
+		},
+		{
+			name:    "parentbased_always_off",
+			sampler: "parentbased_always_off",
+			want:    sdktrace.ParentBased(sdktrace.NeverSample()),
+		},
+		{
+			name:    "parentbased_traceidratio",
+			sampler: "parentbased_traceidratio",
+			arg:     "0.5",
+			want:    sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.5)),
+		},
+		{
+			name:    "invalid arg",
+			sampler: "traceidratio",
+			arg:     "half",
+			want:    sdktrace.TraceIDRatioBased(1),
+			warned:  true,
+		},
+		{
+			name:    "negative arg",
+			sampler: "traceidratio",
+			arg:     "-0.1",
+			want:    sdktrace.TraceIDRatioBased(1),
+			warned:  true,
+		},
+		{
+			name:    "arg above one",
+			sampler: "parentbased_traceidratio",
+			arg:     "1.5",
+			want:    sdktrace.ParentBased(sdktrace.TraceIDRatioBased(1)),
+			warned:  true,
+		},
+		{name: "unsupported sampler", sampler: "jaeger_remote", want: nil, warned: true},
+		{name: "prefixed", sampler: "always_off", prefix: "ACME", want: sdktrace.NeverSample()},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			samplerVar, argVar := "OTEL_TRACES_SAMPLER", "OTEL_TRACES_SAMPLER_ARG"
+			if tt.prefix != "" {
+				t.Setenv(samplerVar, "always_on")
+				samplerVar, argVar = tt.prefix+"_"+samplerVar, tt.prefix+"_"+argVar
+			}
+			t.Setenv(samplerVar, tt.sampler)
+			t.Setenv(argVar, tt.arg)
+
+			core, logs := observer.New(zapcore.DebugLevel)
+			sampler := samplerFromEnv(tt.prefix, otelzap.New(zap.New(core)))
+
+			if tt.want == nil {
+				assert.Nil(t, sampler)
+			} else {
+				require.NotNil(t, sampler)
+				assert.Equal(t, tt.want.Description(), sampler.Description())
+			}
+
+			if tt.warned {
+				assert.Equal(t, 1, logs.FilterLevelExact(zapcore.WarnLevel).Len())
+			} else {
+				assert.Zero(t, logs.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package otelprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Provider is implemented by the log, trace and meter providers of the OTel
+// SDK returned by NewLogger, NewTracer and NewMeterProvider.
+type Provider interface {
+	ForceFlush(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+// ShutdownAll force-flushes all providers and then shuts them down, both in the
+// order given, and returns the errors of all steps joined. A failing provider
+// does not keep the remaining ones from being flushed and shut down.
+//
+// Pass the logger provider last, so that the logs written while flushing and
+// shutting down the other providers, e.g. about failed exports, are still
+// exported:
+//
+//	defer otelprovider.ShutdownAll(ctx, traceProvider, meterProvider, logProvider)
+func ShutdownAll(ctx context.Context, providers ...Provider) error {
+	var errs []error
+
+	for _, provider := range providers {
+		if err := provider.ForceFlush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush %T: %w", provider, err))
+		}
+	}
+
+	for _, provider := range providers {
+		if err := provider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shut down %T: %w", provider, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,56 @@
+package otelprovider
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// WithLogStdout exports the log records to stdout, pretty-printed in their
+// full OTel representation, for local development without a collector. The
+// records are exported synchronously with a simple processor, so don't use it
+// in production.
+func WithLogStdout() LoggerOption {
+	return func(t *Logger) {
+		t.endpoints = append(t.endpoints, stdoutLogEndpoint())
+	}
+}
+
+func stdoutLogEndpoint() LoggerOption {
+	return func(t *Logger) {
+		stdoutExporter, err := stdoutlog.New(stdoutlog.WithPrettyPrint())
+		if err != nil {
+			t.errs = append(t.errs, fmt.Errorf("failed to create stdout log exporter: %w", err))
+			return
+		}
+
+		t.providerOptions = append(t.providerOptions, log.WithProcessor(log.NewSimpleProcessor(stdoutExporter)))
+	}
+}
+
+// WithTraceStdout exports the spans to stdout, pretty-printed in their full
+// OTel representation, for local development without a collector. The spans
+// are exported synchronously with a simple span processor, so don't use it in
+// production.
+func WithTraceStdout() TracerOption {
+	return func(t *Tracer) {
+		t.endpoints = append(t.endpoints, stdoutTraceEndpoint())
+	}
+}
+
+func stdoutTraceEndpoint() TracerOption {
+	return func(t *Tracer) {
+		stdoutExporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			t.errs = append(t.errs, fmt.Errorf("failed to create stdout trace exporter: %w", err))
+			return
+		}
+
+		simple := trace.NewSimpleSpanProcessor(stdoutExporter)
+
+		t.addSpanProcessor(t.exportingSpanProcessor(simple), ExporterSpanProcessorPriority)
+	}
+}
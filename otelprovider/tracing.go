@@ -2,31 +2,89 @@ package otelprovider
 
 import (
 	"context"
-	"os"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spechtlabs/go-otel-utils/otelzap"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
 )
 
+// ExporterSpanProcessorPriority is the priority of the batch span processors
+// created for the OTLP endpoints. Span processors with a lower priority run
+// before them, see WithTraceSpanProcessorPriority.
+const ExporterSpanProcessorPriority = 100
+
 type Tracer struct {
 	providerOptions []trace.TracerProviderOption
 	insecure        bool
 	resources       *resource.Resource
-	register        bool
+	detectors       bool
+	// resourceDetectors are the additional detectors set with
+	// WithTraceResourceDetectors.
+	resourceDetectors []resource.Detector
+	register          bool
+
+	// endpoints create the exporters once all options have been applied,
+	// so that the order in which options are passed does not matter.
+	endpoints          []TracerOption
+	grpcConnectBackoff *backoff.Config
+	retry              *RetryConfig
+	exportTimeout      time.Duration
+	resourceAttributes []attribute.KeyValue
+	spanProcessors     []prioritizedSpanProcessor
+	sampler            trace.Sampler
+	keepErrors         bool
+	automaticEnv       bool
+	envPrefix          string
+	defaultLocalhost   bool
+	headers            map[string]string
+	tlsConfig          *tls.Config
+	compression        string
+	srvService         string
+	srvResolved        bool
+
+	// diagnostics logs the problems with the configuration, see
+	// WithTraceDiagnosticLogger.
+	diagnostics *otelzap.Logger
+
+	// errs collects the errors of creating the exporters.
+	errs []error
+}
+
+type prioritizedSpanProcessor struct {
+	processor trace.SpanProcessor
+	priority  int
 }
 
-func NewTracer(opts ...TracerOption) *trace.TracerProvider {
+// NewTracer creates a TracerProvider exporting the spans to the configured
+// endpoints, and registers it globally unless WithoutRegisterTraceProvider is
+// given.
+//
+// Failing to create an exporter, e.g. due to an invalid endpoint, does not keep
+// the provider from being created: the exporter is left out and the error is
+// returned along with the provider, which the application may keep using.
+// If the resource describing the service can't be created, no provider is
+// created and the error is returned.
+func NewTracer(opts ...TracerOption) (*trace.TracerProvider, error) {
 	t := &Tracer{
 		insecure:        false,
 		providerOptions: []trace.TracerProviderOption{},
-		resources:       newOtelResources(),
+		detectors:       true,
 		register:        true,
 	}
 
@@ -34,6 +92,59 @@ func NewTracer(opts ...TracerOption) *trace.TracerProvider {
 		opt(t)
 	}
 
+	if t.diagnostics == nil {
+		t.diagnostics = otelzap.L()
+	}
+
+	if t.resources == nil {
+		resources, err := cachedOtelResources(t.detectors, t.diagnostics)
+		if err != nil {
+			return nil, err
+		}
+		t.resources = resources
+	}
+
+	if len(t.resourceDetectors) > 0 {
+		resources, err := mergeDetectedResource(t.resources, t.resourceDetectors, t.diagnostics)
+		if err != nil {
+			return nil, err
+		}
+		t.resources = resources
+	}
+
+	if t.srvService != "" {
+		t.applySRVEndpoint()
+	}
+
+	if t.automaticEnv {
+		t.applyAutomaticEnv()
+	}
+
+	if t.tlsConfig != nil && t.insecure {
+		t.diagnostics.Warn("Both a TLS config and an insecure connection are configured for the OTLP trace exporters, using the TLS config")
+		t.insecure = false
+	}
+
+	t.compression = validCompression(t.compression, t.diagnostics)
+
+	for _, endpoint := range t.endpoints {
+		endpoint(t)
+	}
+
+	// Register the span processors in the order of their priority. The
+	// provider calls OnStart and OnEnd in registration order.
+	sort.SliceStable(t.spanProcessors, func(i, j int) bool {
+		return t.spanProcessors[i].priority < t.spanProcessors[j].priority
+	})
+	for _, sp := range t.spanProcessors {
+		t.providerOptions = append(t.providerOptions, trace.WithSpanProcessor(sp.processor))
+	}
+
+	if sampler := t.traceSampler(); sampler != nil {
+		t.providerOptions = append(t.providerOptions, trace.WithSampler(sampler))
+	}
+
+	t.resources = mergeResourceAttributes(t.resources, t.resourceAttributes, t.diagnostics)
 	t.providerOptions = append(t.providerOptions, trace.WithResource(t.resources))
 	traceProvider := trace.NewTracerProvider(t.providerOptions...)
 
@@ -42,7 +153,19 @@ func NewTracer(opts ...TracerOption) *trace.TracerProvider {
 		otel.SetTracerProvider(traceProvider)
 	}
 
-	return traceProvider
+	return traceProvider, errors.Join(t.errs...)
+}
+
+func (t *Tracer) batchSpanProcessorOptions() []trace.BatchSpanProcessorOption {
+	var opts []trace.BatchSpanProcessorOption
+	if t.exportTimeout > 0 {
+		opts = append(opts, trace.WithExportTimeout(t.exportTimeout))
+	}
+	return opts
+}
+
+func (t *Tracer) addSpanProcessor(processor trace.SpanProcessor, priority int) {
+	t.spanProcessors = append(t.spanProcessors, prioritizedSpanProcessor{processor: processor, priority: priority})
 }
 
 // TracerOption applies a configuration to the given config.
@@ -54,77 +177,424 @@ func WithTraceInsecure() TracerOption {
 	}
 }
 
+// WithTraceTLSConfig sets the TLS configuration of the connection to the
+// collector, e.g. to trust a private CA. It takes precedence over
+// WithTraceInsecure, with a warning logged if both are set.
+func WithTraceTLSConfig(config *tls.Config) TracerOption {
+	return func(t *Tracer) {
+		t.tlsConfig = config
+	}
+}
+
+// WithGrpcTraceEndpoint exports the spans to the OTLP gRPC endpoint, e.g.
+// "localhost:4317". Endpoint options accumulate: every endpoint, including the
+// ones added by WithHttpTraceEndpoint, WithTraceStdout and
+// WithTraceAutomaticEnv, gets its own exporter and span processor, so spans
+// can be sent to several collectors at once. The endpoint may also be given as
+// URL, e.g. "http://localhost:4317", with the http scheme making the
+// connection insecure.
 func WithGrpcTraceEndpoint(otelGrpcEndpoint string) TracerOption {
 	return func(t *Tracer) {
+		t.endpoints = append(t.endpoints, grpcTraceEndpoint(otelGrpcEndpoint))
+	}
+}
+
+func grpcTraceEndpoint(otelGrpcEndpoint string) TracerOption {
+	return func(t *Tracer) {
+		endpoint, err := parseEndpoint(otelGrpcEndpoint)
+		if err != nil {
+			t.errs = append(t.errs, err)
+			return
+		}
+
 		grpcExporterOptions := []otlptracegrpc.Option{
-			otlptracegrpc.WithEndpoint(otelGrpcEndpoint),
+			otlptracegrpc.WithEndpoint(endpoint.hostPort),
 		}
 
-		if t.insecure {
+		if t.insecure || endpoint.insecure {
 			grpcExporterOptions = append(grpcExporterOptions, otlptracegrpc.WithInsecure())
 		}
 
-		grpcExporter, err := otlptrace.New(context.Background(), otlptracegrpc.NewClient(grpcExporterOptions...))
+		if t.compression == gzipCompression {
+			grpcExporterOptions = append(grpcExporterOptions, otlptracegrpc.WithCompressor(gzipCompression))
+		}
+
+		if t.tlsConfig != nil {
+			grpcExporterOptions = append(grpcExporterOptions, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(t.tlsConfig)))
+		}
+
+		if len(t.headers) > 0 {
+			grpcExporterOptions = append(grpcExporterOptions, otlptracegrpc.WithHeaders(t.headers))
+		}
+
+		if t.grpcConnectBackoff != nil {
+			grpcExporterOptions = append(grpcExporterOptions, otlptracegrpc.WithDialOption(
+				grpc.WithConnectParams(grpc.ConnectParams{
+					Backoff:           *t.grpcConnectBackoff,
+					MinConnectTimeout: grpcMinConnectTimeout,
+				}),
+			))
+		}
+
+		if t.retry != nil {
+			grpcExporterOptions = append(grpcExporterOptions, otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig(t.retry.exporterConfig())))
+		}
+
+		if t.exportTimeout > 0 {
+			grpcExporterOptions = append(grpcExporterOptions, otlptracegrpc.WithTimeout(t.exportTimeout))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), exporterSetupTimeout)
+		defer cancel()
+
+		grpcExporter, err := otlptrace.New(ctx, otlptracegrpc.NewClient(grpcExporterOptions...))
 		if err != nil {
-			otelzap.L().Sugar().Fatalw("Failed to create OTLP gRPC trace exporter", zap.Error(err))
+			t.errs = append(t.errs, fmt.Errorf("failed to create OTLP gRPC trace exporter: %w", err))
+			return
 		}
 
-		batcher := trace.NewBatchSpanProcessor(grpcExporter)
+		batcher := trace.NewBatchSpanProcessor(grpcExporter, t.batchSpanProcessorOptions()...)
 
-		t.providerOptions = append(t.providerOptions, trace.WithSpanProcessor(batcher))
+		t.addSpanProcessor(t.exportingSpanProcessor(batcher), ExporterSpanProcessorPriority)
 	}
 }
 
+// WithHttpTraceEndpoint exports the spans to the OTLP HTTP endpoint, e.g.
+// "localhost:4318". Like all endpoint options, it adds an exporter rather than
+// replacing the ones configured by other options. The endpoint may also be
+// given as URL, e.g. "https://collector.example.com/otlp/v1/traces": its path
+// replaces the default "/v1/traces", and the http scheme makes the connection
+// insecure.
 func WithHttpTraceEndpoint(otelHttpEndpoint string) TracerOption {
 	return func(t *Tracer) {
+		t.endpoints = append(t.endpoints, httpTraceEndpoint(otelHttpEndpoint))
+	}
+}
+
+func httpTraceEndpoint(otelHttpEndpoint string) TracerOption {
+	return func(t *Tracer) {
+		endpoint, err := parseEndpoint(otelHttpEndpoint)
+		if err != nil {
+			t.errs = append(t.errs, err)
+			return
+		}
+
 		httpExporterOptions := []otlptracehttp.Option{
-			otlptracehttp.WithEndpoint(otelHttpEndpoint),
+			otlptracehttp.WithEndpoint(endpoint.hostPort),
 		}
 
-		if t.insecure {
+		if t.insecure || endpoint.insecure {
 			httpExporterOptions = append(httpExporterOptions, otlptracehttp.WithInsecure())
 		}
 
-		httpExporter, err := otlptrace.New(context.Background(), otlptracehttp.NewClient(httpExporterOptions...))
+		if endpoint.path != "" {
+			httpExporterOptions = append(httpExporterOptions, otlptracehttp.WithURLPath(endpoint.path))
+		}
+
+		switch t.compression {
+		case gzipCompression:
+			httpExporterOptions = append(httpExporterOptions, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		case noCompression:
+			httpExporterOptions = append(httpExporterOptions, otlptracehttp.WithCompression(otlptracehttp.NoCompression))
+		}
+
+		if t.tlsConfig != nil {
+			httpExporterOptions = append(httpExporterOptions, otlptracehttp.WithTLSClientConfig(t.tlsConfig))
+		}
+
+		if len(t.headers) > 0 {
+			httpExporterOptions = append(httpExporterOptions, otlptracehttp.WithHeaders(t.headers))
+		}
+
+		if t.retry != nil {
+			httpExporterOptions = append(httpExporterOptions, otlptracehttp.WithRetry(otlptracehttp.RetryConfig(t.retry.exporterConfig())))
+		}
+
+		if t.exportTimeout > 0 {
+			httpExporterOptions = append(httpExporterOptions, otlptracehttp.WithTimeout(t.exportTimeout))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), exporterSetupTimeout)
+		defer cancel()
+
+		httpExporter, err := otlptrace.New(ctx, otlptracehttp.NewClient(httpExporterOptions...))
 		if err != nil {
-			otelzap.L().Sugar().Fatalw("Failed to create OTLP gRPC trace exporter", zap.Error(err))
+			t.errs = append(t.errs, fmt.Errorf("failed to create OTLP HTTP trace exporter: %w", err))
+			return
 		}
 
-		batcher := trace.NewBatchSpanProcessor(httpExporter)
+		batcher := trace.NewBatchSpanProcessor(httpExporter, t.batchSpanProcessorOptions()...)
 
-		t.providerOptions = append(t.providerOptions, trace.WithSpanProcessor(batcher))
+		t.addSpanProcessor(t.exportingSpanProcessor(batcher), ExporterSpanProcessorPriority)
 	}
 }
 
-func WithTraceAutomaticEnv() TracerOption {
+// WithTraceGrpcConnectBackoff configures the backoff the gRPC trace exporter
+// uses when (re-)connecting to the collector. Increasing the delays reduces
+// the reconnect pressure on a flapping collector. It has no effect on the HTTP
+// exporter.
+//
+// The default is gRPC's backoff.DefaultConfig: a base delay of 1s, a
+// multiplier of 1.6, a jitter of 0.2 and a maximum delay of 120s.
+func WithTraceGrpcConnectBackoff(config backoff.Config) TracerOption {
 	return func(t *Tracer) {
-		otelEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-		if otelEndpoint == "" {
-			return // if no endpoint is set, do not configure the exporter
-		}
+		t.grpcConnectBackoff = &config
+	}
+}
+
+// WithTraceRetry sets how the OTLP trace exporters retry failed exports, e.g.
+// while the collector is unavailable. Setting MaxElapsedTime to 0 disables
+// retries.
+//
+// The default is DefaultRetryConfig.
+func WithTraceRetry(config RetryConfig) TracerOption {
+	return func(t *Tracer) {
+		t.retry = &config
+	}
+}
 
-		otelInsecure := os.Getenv("OTEL_EXPORTER_OTLP_INSECURE") == "true"
+// WithTraceExportTimeout sets how long the batch span processor and the OTLP
+// exporters wait for a single export of spans to complete before it is
+// cancelled. It overrides OTEL_EXPORTER_OTLP_TIMEOUT.
+//
+// The default is 30s, or the value of OTEL_BSP_EXPORT_TIMEOUT if set, for the
+// batch span processor and 10s, or the value of OTEL_EXPORTER_OTLP_TIMEOUT if
+// set, for the exporters. Independent of it, creating an exporter is bounded
+// to 10s.
+func WithTraceExportTimeout(timeout time.Duration) TracerOption {
+	return func(t *Tracer) {
+		t.exportTimeout = timeout
+	}
+}
 
-		if otelInsecure {
-			WithTraceInsecure()(t)
-		}
+// WithTraceCompression sets the compression of the export requests, either
+// "gzip" or "none". Other values are ignored with a warning. By default,
+// export requests are not compressed.
+func WithTraceCompression(compression string) TracerOption {
+	return func(t *Tracer) {
+		t.compression = compression
+	}
+}
 
-		if strings.Contains(otelEndpoint, "4317") {
-			WithGrpcTraceEndpoint(otelEndpoint)(t)
-		} else if strings.Contains(otelEndpoint, "4318") {
-			WithHttpTraceEndpoint(otelEndpoint)(t)
+// WithTraceHeaders sets headers sent with every export request, e.g. to
+// authenticate with a managed backend. They apply to both the gRPC and the
+// HTTP exporter. Headers set by multiple calls are merged, with later values
+// overriding earlier ones for the same key.
+func WithTraceHeaders(headers map[string]string) TracerOption {
+	return func(t *Tracer) {
+		if t.headers == nil {
+			t.headers = make(map[string]string, len(headers))
+		}
+		for key, value := range headers {
+			t.headers[key] = value
 		}
 	}
 }
 
+// WithTraceAutomaticEnv configures the OTLP endpoint from the environment: the
+// endpoint is read from OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, falling back to
+// OTEL_EXPORTER_OTLP_ENDPOINT. The exporter is selected by
+// OTEL_EXPORTER_OTLP_TRACES_PROTOCOL or OTEL_EXPORTER_OTLP_PROTOCOL, "grpc" or
+// "http/protobuf", and otherwise by the port: gRPC for 4317 and HTTP for any
+// other port. The connection is insecure if
+// OTEL_EXPORTER_OTLP_INSECURE is "true". The headers listed in
+// OTEL_EXPORTER_OTLP_HEADERS are added to those set with WithTraceHeaders,
+// which take precedence. Unless set with WithTraceCompression,
+// OTEL_EXPORTER_OTLP_COMPRESSION selects the compression. Unless a sampler is
+// set with WithTraceSampler, the sampler is configured by OTEL_TRACES_SAMPLER
+// and OTEL_TRACES_SAMPLER_ARG. See WithTraceEnvPrefix to read prefixed
+// variables instead.
+//
+// The endpoint from the environment is added to the endpoints configured by
+// other options, unless WithTraceEndpointFromSRV resolved one, which takes
+// precedence. If the environment sets no endpoint, no exporter is added,
+// unless WithTraceDefaultLocalhostEndpoint is set.
+func WithTraceAutomaticEnv() TracerOption {
+	return func(t *Tracer) {
+		t.automaticEnv = true
+	}
+}
+
+// WithTraceEnvPrefix makes WithTraceAutomaticEnv read the environment variables
+// prefixed with the given prefix, e.g. ACME_OTEL_EXPORTER_OTLP_ENDPOINT for
+// the prefix "ACME", falling back to the standard variables if the prefixed
+// ones are not set. By default, no prefix is used.
+func WithTraceEnvPrefix(prefix string) TracerOption {
+	return func(t *Tracer) {
+		t.envPrefix = prefix
+	}
+}
+
+func (t *Tracer) applyAutomaticEnv() {
+	if t.sampler == nil {
+		t.sampler = samplerFromEnv(t.envPrefix, t.diagnostics)
+	}
+
+	otelEndpoint := envEndpoint(t.envPrefix, "traces")
+	if otelEndpoint == "" && !t.srvResolved && !t.defaultLocalhost {
+		return // if no endpoint is set, do not configure the exporter
+	}
+
+	otelInsecure := getenv(t.envPrefix, "OTEL_EXPORTER_OTLP_INSECURE") == "true"
+
+	if otelInsecure {
+		WithTraceInsecure()(t)
+	}
+
+	if t.compression == "" {
+		t.compression = getenv(t.envPrefix, "OTEL_EXPORTER_OTLP_COMPRESSION")
+	}
+
+	t.headers = mergeEnvHeaders(t.headers, getenv(t.envPrefix, "OTEL_EXPORTER_OTLP_HEADERS"))
+
+	if t.srvResolved {
+		return // the endpoint resolved from the SRV record takes precedence
+	}
+
+	otelEndpoint, protocol := envExporter(t.envPrefix, "traces", otelEndpoint, t.diagnostics)
+	if protocol == grpcProtocol {
+		WithGrpcTraceEndpoint(otelEndpoint)(t)
+	} else {
+		WithHttpTraceEndpoint(otelEndpoint)(t)
+	}
+}
+
+// WithTraceEndpointFromSRV resolves the OTLP endpoint from the DNS SRV record
+// of the given service, e.g. "_otlp._tcp.collector.example.com". The target
+// with the lowest priority (picked by weight if there are several) is used,
+// with the HTTP exporter if its port is 4318 and the gRPC exporter otherwise.
+//
+// The record is resolved once when the TracerProvider is created and not
+// refreshed later on, waiting at most 5 seconds for DNS. If it can't be
+// resolved, a warning is logged and the endpoint is configured from the
+// environment like WithTraceAutomaticEnv does. If it is resolved, it takes
+// precedence over the endpoint from the environment, which is then ignored
+// even with WithTraceAutomaticEnv, while the other variables like the headers
+// still apply. Endpoints added by the endpoint options are kept either way.
+func WithTraceEndpointFromSRV(service string) TracerOption {
+	return func(t *Tracer) {
+		t.srvService = service
+	}
+}
+
+func (t *Tracer) applySRVEndpoint() {
+	endpoint, err := lookupSRVEndpoint(context.Background(), t.srvService)
+	if err != nil {
+		t.diagnostics.Warn("Failed to resolve OTLP trace endpoint from SRV record, falling back to the environment",
+			zap.String("service", t.srvService), zap.Error(err))
+		t.automaticEnv = true
+		return
+	}
+
+	t.srvResolved = true
+	if strings.HasSuffix(endpoint, ":4318") {
+		WithHttpTraceEndpoint(endpoint)(t)
+	} else {
+		WithGrpcTraceEndpoint(endpoint)(t)
+	}
+}
+
 func WithTraceResources(res *resource.Resource) TracerOption {
 	return func(t *Tracer) {
 		t.resources = res
 	}
 }
 
+// WithoutTraceDefaultDetectors leaves the host, process and OS attributes out
+// of the resource, which only describes the service and the OTel SDK then. It
+// has no effect if the resource is set with WithTraceResources.
+func WithoutTraceDefaultDetectors() TracerOption {
+	return func(t *Tracer) {
+		t.detectors = false
+	}
+}
+
+// WithTraceResourceDetectors adds the attributes detected by the given
+// detectors, e.g. the ones for cloud environments from the OTel contrib
+// repository, to the resource, overriding detected attributes with the same
+// key. It works with both the default resource and the one set with
+// WithTraceResources. If a detector fails, creating the provider fails, unless
+// only some attributes could not be detected, which is only warned about.
+func WithTraceResourceDetectors(detectors ...resource.Detector) TracerOption {
+	return func(t *Tracer) {
+		t.resourceDetectors = append(t.resourceDetectors, detectors...)
+	}
+}
+
+// WithTraceResourceAttributes merges the given attributes into the resource,
+// overriding attributes with the same key. Attributes with an empty key or an
+// invalid or empty value are skipped with a warning.
+func WithTraceResourceAttributes(attrs ...attribute.KeyValue) TracerOption {
+	return func(t *Tracer) {
+		t.resourceAttributes = append(t.resourceAttributes, attrs...)
+	}
+}
+
+// WithTraceServiceName sets the service.name resource attribute, overriding
+// OTEL_SERVICE_NAME, OTEL_RESOURCE_ATTRIBUTES and the name of the executable
+// it defaults to.
+func WithTraceServiceName(name string) TracerOption {
+	return WithTraceResourceAttributes(semconv.ServiceName(name))
+}
+
+// WithTraceServiceVersion sets the service.version resource attribute,
+// overriding OTEL_SERVICE_VERSION and OTEL_RESOURCE_ATTRIBUTES, e.g. to set it
+// from the build info.
+func WithTraceServiceVersion(version string) TracerOption {
+	return WithTraceResourceAttributes(semconv.ServiceVersion(version))
+}
+
+// WithTraceServiceInstanceID sets the service.instance.id resource attribute,
+// which distinguishes the replicas of a service. It overrides
+// OTEL_RESOURCE_ATTRIBUTES and the default: the HOSTNAME environment variable,
+// or a random UUID generated once per process if it is not set.
+func WithTraceServiceInstanceID(id string) TracerOption {
+	return WithTraceResourceAttributes(semconv.ServiceInstanceID(id))
+}
+
+// WithTraceSpanProcessor registers a span processor running before the batch
+// span processors exporting to the OTLP endpoints, so that it may still modify
+// spans before they are captured for export. It is equivalent to
+// WithTraceSpanProcessorPriority with a priority of 0.
+func WithTraceSpanProcessor(processor trace.SpanProcessor) TracerOption {
+	return WithTraceSpanProcessorPriority(processor, 0)
+}
+
+// WithTraceSpanProcessorPriority registers a span processor with the given
+// priority. OnStart and OnEnd of the span processors are called in ascending
+// order of their priority, and in the order the options were given for equal
+// priorities. The batch span processors of the OTLP endpoints have a priority
+// of ExporterSpanProcessorPriority, so processors modifying spans must have a
+// lower priority than that to affect the exported spans.
+func WithTraceSpanProcessorPriority(processor trace.SpanProcessor, priority int) TracerOption {
+	return func(t *Tracer) {
+		t.addSpanProcessor(processor, priority)
+	}
+}
+
 func WithoutRegisterTraceProvider() TracerOption {
 	return func(t *Tracer) {
 		t.register = false
 	}
 }
+
+// WithTraceDiagnosticLogger sets the logger the problems with the configuration
+// of the tracer provider, e.g. an unsupported compression or invalid resource
+// attributes, are logged to. By default, they are logged to the global
+// otelzap.L(), which may not be set up yet while the providers are created.
+func WithTraceDiagnosticLogger(logger *otelzap.Logger) TracerOption {
+	return func(t *Tracer) {
+		t.diagnostics = logger
+	}
+}
+
+// WithTraceDefaultLocalhostEndpoint makes WithTraceAutomaticEnv export the
+// spans to the local collector if the environment sets no endpoint: to
+// "http://localhost:4317" via gRPC, or to "http://localhost:4318" via HTTP if
+// the protocol variables select "http/protobuf". By default, no exporter is
+// added then.
+func WithTraceDefaultLocalhostEndpoint(enabled bool) TracerOption {
+	return func(t *Tracer) {
+		t.defaultLocalhost = enabled
+	}
+}
@@ -4,11 +4,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"unicode/utf8"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/log"
 )
 
+// The default keys of the caller attributes, see WithCodeAttributeKeys.
+const (
+	codeFunctionKey = "code.function"
+	codeFilepathKey = "code.filepath"
+	codeLinenoKey   = "code.lineno"
+)
+
+// Attribute converts a value to an OTel attribute, as used to annotate spans.
+// Slices of basic types are kept as slice attributes, other values are
+// converted to strings, falling back to their JSON representation.
 func Attribute(key string, value interface{}) attribute.KeyValue {
 	switch value := value.(type) {
 	case nil:
@@ -67,6 +78,32 @@ func Attribute(key string, value interface{}) attribute.KeyValue {
 	return attribute.String(key, fmt.Sprint(value))
 }
 
+// truncationMarker is appended to the string attribute values truncated by
+// truncateAttribute.
+const truncationMarker = "...(truncated)"
+
+// truncateAttribute truncates a string attribute value longer than maxLength
+// bytes to maxLength bytes, cut at a rune boundary and followed by
+// truncationMarker. A non-positive maxLength disables truncation.
+func truncateAttribute(kv attribute.KeyValue, maxLength int) attribute.KeyValue {
+	if maxLength <= 0 || kv.Value.Type() != attribute.STRING {
+		return kv
+	}
+
+	str := kv.Value.AsString()
+	if len(str) <= maxLength {
+		return kv
+	}
+
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(str[cut]) {
+		cut--
+	}
+	return attribute.String(string(kv.Key), str[:cut]+truncationMarker)
+}
+
+// LogValue converts a value to an OTel log value, keeping slices as slice
+// values and converting other values like Attribute does.
 func LogValue(value interface{}) log.Value {
 	switch value := value.(type) {
 	case nil:
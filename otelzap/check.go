@@ -0,0 +1,60 @@
+package otelzap
+
+import (
+	"context"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Enabled reports whether a message at the given level is written to zap or
+// emitted to OTel. It accounts for both the levels the message is written to
+// zap on (the level of the zap core and WithZapLevel) and the level configured
+// with WithMinLevel, which may differ in either direction, so it's true if any
+// of them is enabled. Use it, or Check, to guard expensive field construction
+// on hot paths.
+func (l *Logger) Enabled(lvl zapcore.Level) bool {
+	return l.writer.Core().Enabled(lvl) || l.minLevel.Enabled(lvl)
+}
+
+// Check returns a CheckedEntry if a message at the given level is written to
+// zap or emitted to OTel, see Enabled, and nil otherwise. The entry is logged
+// without context, like Ctx(context.Background()).Check.
+//
+// Panic and Fatal entries always return a CheckedEntry, as they panic or exit
+// even if their level is disabled.
+func (l *Logger) Check(lvl zapcore.Level, msg string) *CheckedEntry {
+	return l.Ctx(context.Background()).Check(lvl, msg)
+}
+
+// Check returns a CheckedEntry if a message at the given level is written to
+// zap or emitted to OTel, see Logger.Enabled, and nil otherwise.
+//
+// For example,
+//
+//	if ce := logger.Ctx(ctx).Check(zap.DebugLevel, "cache state"); ce != nil {
+//		ce.Write(zap.Any("entries", cache.Dump()))
+//	}
+func (l LoggerWithCtx) Check(lvl zapcore.Level, msg string) *CheckedEntry {
+	if lvl < zapcore.DPanicLevel && !l.l.Enabled(lvl) {
+		return nil
+	}
+
+	return &CheckedEntry{l: l, lvl: lvl, msg: msg}
+}
+
+// CheckedEntry is a log message at a level that is enabled, returned by
+// Check. The fields are only passed once the entry is written.
+type CheckedEntry struct {
+	l   LoggerWithCtx
+	lvl zapcore.Level
+	msg string
+}
+
+// Write logs the entry with the given fields, like the logging method of its
+// level would. It's safe to call on a nil CheckedEntry.
+func (ce *CheckedEntry) Write(fields ...zapcore.Field) {
+	if ce == nil {
+		return
+	}
+	ce.l.write(ce.lvl, ce.msg, fields)
+}
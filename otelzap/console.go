@@ -0,0 +1,53 @@
+package otelzap
+
+import (
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewDevConsole creates a new Logger that writes human-readable logs to
+// stderr using the encoder config returned by NewDevConsoleEncoderConfig.
+// It is meant for local development, where the console output should be easy
+// to compare with the records showing up in the OTel backend.
+func NewDevConsole(opts ...Option) *Logger {
+	core := zapcore.NewCore(
+		zapcore.NewConsoleEncoder(NewDevConsoleEncoderConfig()),
+		zapcore.Lock(os.Stderr),
+		zap.NewAtomicLevelAt(zapcore.DebugLevel),
+	)
+
+	return New(zap.New(core, zap.AddCaller(), zap.Development()), opts...)
+}
+
+// NewDevConsoleEncoderConfig returns a zapcore.EncoderConfig whose keys, level
+// labels and time format match the OTel log records emitted by this package:
+// levels are rendered as OTel severity texts (e.g. INFO, WARN, FATAL2), times
+// as UTC RFC 3339 timestamps and the caller under the code.* semantic
+// convention keys.
+func NewDevConsoleEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "timestamp",
+		LevelKey:       "severity_text",
+		NameKey:        "otel.scope.name",
+		CallerKey:      codeFilepathKey,
+		FunctionKey:    codeFunctionKey,
+		MessageKey:     "body",
+		StacktraceKey:  "exception.stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    severityLevelEncoder,
+		EncodeTime:     utcRFC3339NanoTimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.FullCallerEncoder,
+	}
+}
+
+func severityLevelEncoder(lvl zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(ConvertLevel(lvl).String())
+}
+
+func utcRFC3339NanoTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(t.UTC().Format(time.RFC3339Nano))
+}
@@ -0,0 +1,73 @@
+package otelzap
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/log"
+	"go.uber.org/zap"
+)
+
+// ContextAttributeConverter converts a context value to the value of an OTel
+// record attribute. It returns false if the attribute should not be added.
+type ContextAttributeConverter func(value any) (log.Value, bool)
+
+type contextAttribute struct {
+	ctxKey  any
+	attrKey string
+	convert ContextAttributeConverter
+}
+
+var (
+	_contextAttributesMu sync.RWMutex
+	_contextAttributes   []contextAttribute
+)
+
+// RegisterContextAttribute registers a context value to be added as attribute
+// to the OTel records of the loggers created with
+// WithRegisteredContextAttributes: if the context passed to the logger holds a
+// value for ctxKey, it is converted with convert and added under attrKey. If
+// convert is nil, the value is converted like a zap.Any field.
+//
+// The registry is global. It's safe for concurrent use, but meant to be set up
+// once at initialization, e.g. in an init function of the package defining the
+// context key.
+func RegisterContextAttribute(ctxKey any, attrKey string, convert ContextAttributeConverter) {
+	if convert == nil {
+		convert = func(value any) (log.Value, bool) {
+			kvs := appendField(nil, zap.Any(attrKey, value))
+			if len(kvs) != 1 {
+				return log.Value{}, false
+			}
+			return kvs[0].Value, true
+		}
+	}
+
+	_contextAttributesMu.Lock()
+	_contextAttributes = append(_contextAttributes, contextAttribute{
+		ctxKey:  ctxKey,
+		attrKey: attrKey,
+		convert: convert,
+	})
+	_contextAttributesMu.Unlock()
+}
+
+// registeredContextAttributes returns the attributes of the registered values
+// present in ctx.
+func registeredContextAttributes(ctx context.Context) []log.KeyValue {
+	_contextAttributesMu.RLock()
+	defer _contextAttributesMu.RUnlock()
+
+	var kvs []log.KeyValue
+	for _, attr := range _contextAttributes {
+		value := ctx.Value(attr.ctxKey)
+		if value == nil {
+			continue
+		}
+
+		if v, ok := attr.convert(value); ok {
+			kvs = append(kvs, log.KeyValue{Key: attr.attrKey, Value: v})
+		}
+	}
+	return kvs
+}
@@ -5,13 +5,17 @@ import (
 	"math"
 	"reflect"
 	"strconv"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel/log"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func convertLevel(level zapcore.Level) log.Severity {
+// ConvertLevel converts a zap level to the OTel log severity. It is the
+// default mapping of the Logger, see WithSeverityMapper.
+func ConvertLevel(level zapcore.Level) log.Severity {
 	switch level {
 	case zapcore.DebugLevel:
 		return log.SeverityDebug
@@ -32,7 +36,10 @@ func convertLevel(level zapcore.Level) log.Severity {
 	}
 }
 
-func convertFields(fields []zapcore.Field) []log.KeyValue {
+// ConvertFields converts zap fields to OTel log attributes the way the Logger
+// does for its records, e.g. errors to exception.type and exception.message
+// and objects to maps.
+func ConvertFields(fields []zapcore.Field) []log.KeyValue {
 	kvs := make([]log.KeyValue, 0, len(fields)+numExtraAttr)
 	for _, field := range fields {
 		kvs = appendField(kvs, field)
@@ -40,6 +47,16 @@ func convertFields(fields []zapcore.Field) []log.KeyValue {
 	return kvs
 }
 
+// stringifyAttributes replaces all values by their string representation.
+func stringifyAttributes(kvs []log.KeyValue) []log.KeyValue {
+	for i, kv := range kvs {
+		if kv.Value.Kind() != log.KindString {
+			kvs[i] = log.String(kv.Key, kv.Value.String())
+		}
+	}
+	return kvs
+}
+
 func appendField(kvs []log.KeyValue, f zapcore.Field) []log.KeyValue {
 	switch f.Type {
 	case zapcore.BoolType:
@@ -85,20 +102,59 @@ func appendField(kvs []log.KeyValue, f zapcore.Field) []log.KeyValue {
 		kvs = append(kvs, log.String("exception.message", err.Error()))
 		return kvs
 	case zapcore.ReflectType:
-		str := fmt.Sprint(f.Interface)
-		return append(kvs, log.String(f.Key, str))
+		return append(kvs, log.KeyValue{Key: f.Key, Value: reflectedValue(f.Interface)})
 	case zapcore.SkipType:
 		return kvs
 
 	case zapcore.ArrayMarshalerType:
-		kv := log.String(f.Key+"_error", "otelzap: zapcore.ArrayMarshalerType is not implemented")
-		return append(kvs, kv)
+		value, err := arrayValue(f.Interface.(zapcore.ArrayMarshaler))
+		return appendMarshaled(kvs, f.Key, value, err)
 	case zapcore.ObjectMarshalerType:
-		kv := log.String(f.Key+"_error", "otelzap: zapcore.ObjectMarshalerType is not implemented")
-		return append(kvs, kv)
+		value, err := objectValue(f.Interface.(zapcore.ObjectMarshaler))
+		return appendMarshaled(kvs, f.Key, value, err)
+
+	default:
+		reportUnhandledFieldType(f)
+		return append(kvs, log.String(f.Key, fieldString(f)))
+	}
+}
+
+// appendMarshaled appends the value of a marshaled object or array, and the
+// error marshaling it, if any, as <key>_error.
+func appendMarshaled(kvs []log.KeyValue, key string, value log.Value, err error) []log.KeyValue {
+	kvs = append(kvs, log.KeyValue{Key: key, Value: value})
+	if err != nil {
+		kvs = append(kvs, log.String(key+"_error", err.Error()))
+	}
+	return kvs
+}
+
+// reportedFieldTypes holds the field types not handled by appendField that
+// were already reported.
+var reportedFieldTypes sync.Map // map[zapcore.FieldType]struct{}
+
+// reportUnhandledFieldType logs a debug message to the zap logger of the
+// global Logger the first time a field type is not handled by appendField.
+func reportUnhandledFieldType(f zapcore.Field) {
+	if _, reported := reportedFieldTypes.LoadOrStore(f.Type, struct{}{}); reported {
+		return
+	}
+
+	L().Logger.Debug("otelzap: unhandled field type, converted to its string representation",
+		zap.Uint8("type", uint8(f.Type)),
+		zap.String("key", f.Key),
+	)
+}
 
+// fieldString returns a best-effort string representation of a field of an
+// unhandled type.
+func fieldString(f zapcore.Field) string {
+	switch {
+	case f.Interface != nil:
+		return fmt.Sprint(f.Interface)
+	case f.String != "":
+		return f.String
 	default:
-		kv := log.String(f.Key+"_error", fmt.Sprintf("otelzap: unknown field type: %v", f))
-		return append(kvs, kv)
+		return strconv.FormatInt(f.Integer, 10)
 	}
 }
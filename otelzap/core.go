@@ -0,0 +1,116 @@
+package otelzap
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// otelCore is a zapcore.Core emitting the entries to OTel, see NewOtelCore.
+type otelCore struct {
+	l *Logger
+
+	// named caches the Loggers the entries of named zap loggers are emitted
+	// with, by logger name.
+	named sync.Map // map[string]*Logger
+}
+
+var _ zapcore.Core = (*otelCore)(nil)
+
+// NewOtelCore returns a zapcore.Core that emits the entries written to it as
+// OTel log records to the given provider, for users composing their own zap
+// cores rather than wrapping the logger with New. Combine it with the cores
+// writing the logs locally with zapcore.NewTee:
+//
+//	core := zapcore.NewTee(consoleCore, otelzap.NewOtelCore(provider))
+//	logger := zap.New(core, zap.AddCaller())
+//
+// The options configure the records like they do for a Logger, e.g.
+// WithMinLevel sets the level of the core. As zap entries carry no context,
+// the records aren't correlated with spans; use Logger.Ctx for that.
+//
+// The records are timestamped with the time of the zap entry, so they agree
+// with the output of the other cores, and WithClock has no effect. The name of
+// the zap logger, e.g. set with zap.Logger.Named, is the instrumentation
+// scope of the records, like with Logger.Named.
+func NewOtelCore(provider log.LoggerProvider, opts ...Option) zapcore.Core {
+	opts = append([]Option{WithLoggerProvider(provider)}, opts...)
+	return &otelCore{l: New(zap.NewNop(), opts...)}
+}
+
+// Enabled reports whether entries at the given level are emitted, see
+// WithMinLevel.
+func (c *otelCore) Enabled(lvl zapcore.Level) bool {
+	return c.l.minLevel.Enabled(lvl)
+}
+
+// With adds structured context to the Core.
+func (c *otelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &otelCore{l: c.l.With(fields...)}
+}
+
+// Check adds the core to the checked entry if the level is enabled.
+func (c *otelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write emits the entry to OTel. The caller of the entry is reported as is, as
+// zap already skipped the frames of the wrappers.
+func (c *otelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.logger(ent.LoggerName).Ctx(context.Background()).logFields(
+		context.Background(), ent.Level, ent.Message, fields, nil, ent.Caller, ent.Time,
+	)
+	return nil
+}
+
+// logger returns the Logger the entries of the zap logger with the given name
+// are emitted with, see Logger.Named.
+func (c *otelCore) logger(name string) *Logger {
+	if name == "" {
+		return c.l
+	}
+	if l, ok := c.named.Load(name); ok {
+		return l.(*Logger)
+	}
+	l, _ := c.named.LoadOrStore(name, c.l.Named(name))
+	return l.(*Logger)
+}
+
+// Sync flushes the buffered records of the provider, see Logger.Sync.
+func (c *otelCore) Sync() error {
+	return c.l.Sync()
+}
+
+// levelCore wraps a zapcore.Core, additionally filtering the entries by level,
+// see WithZapLevel.
+type levelCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+}
+
+var _ zapcore.Core = (*levelCore)(nil)
+
+// Enabled reports whether the level is enabled by both the filter and the
+// wrapped core.
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+// With adds structured context to the wrapped Core.
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+// Check passes the entry on to the wrapped core if the level is enabled.
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.level.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
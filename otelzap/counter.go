@@ -0,0 +1,115 @@
+package otelzap
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// counters creates and caches the counters incremented by LoggerWithCtx.Count.
+type counters struct {
+	meter    metric.Meter
+	counters sync.Map // map[string]metric.Int64Counter
+}
+
+func newCounters(meter metric.Meter) *counters {
+	return &counters{meter: meter}
+}
+
+func (c *counters) add(ctx context.Context, name string, fields []zapcore.Field) {
+	counter, ok := c.counters.Load(name)
+	if !ok {
+		created, err := c.meter.Int64Counter(name)
+		if err != nil {
+			// The meter still returns a usable counter alongside the error.
+			otel.Handle(err)
+		}
+		counter, _ = c.counters.LoadOrStore(name, created)
+	}
+
+	counter.(metric.Int64Counter).Add(ctx, 1, metric.WithAttributes(metricAttributes(fields)...))
+}
+
+// metricAttributes converts the fields to the attributes of a measurement,
+// keeping bool, integer, float and string values typed and converting other
+// values to strings. Error fields are skipped, since their messages would
+// create a time series per message.
+func metricAttributes(fields []zapcore.Field) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(fields))
+	for _, field := range fields {
+		if field.Type == zapcore.ErrorType {
+			continue
+		}
+
+		for _, kv := range ConvertFields([]zapcore.Field{field}) {
+			attrs = append(attrs, metricAttribute(kv))
+		}
+	}
+	return attrs
+}
+
+func metricAttribute(kv log.KeyValue) attribute.KeyValue {
+	switch kv.Value.Kind() {
+	case log.KindBool:
+		return attribute.Bool(kv.Key, kv.Value.AsBool())
+	case log.KindInt64:
+		return attribute.Int64(kv.Key, kv.Value.AsInt64())
+	case log.KindFloat64:
+		return attribute.Float64(kv.Key, kv.Value.AsFloat64())
+	case log.KindString:
+		return attribute.String(kv.Key, kv.Value.AsString())
+	default:
+		return attribute.String(kv.Key, kv.Value.String())
+	}
+}
+
+// Count logs a message at InfoLevel, like Info, and increments the counter
+// with the given name by one, using the fields passed at the log site as the
+// attributes of the measurement. Fields accumulated on the logger are not
+// added to the measurement, and neither are error fields, which are only
+// logged.
+//
+// The counter is created from the MeterProvider configured with
+// WithMeterProvider. Without one, Count only logs the message. Keep in mind
+// that every distinct combination of field values creates a new time series.
+func (l LoggerWithCtx) Count(msg string, counterName string, fields ...zapcore.Field) {
+	if l.l.counters != nil {
+		l.l.counters.add(l.ctx, counterName, l.l.redactFields(fields))
+	}
+
+	l.write(zap.InfoLevel, msg, fields)
+}
+
+// recordCounter counts the records emitted to OTel by severity, see
+// WithLogMetrics. The counter is created on the first emitted record.
+type recordCounter struct {
+	meter   metric.Meter
+	once    sync.Once
+	counter metric.Int64Counter
+}
+
+func newRecordCounter(meter metric.Meter) *recordCounter {
+	return &recordCounter{meter: meter}
+}
+
+func (c *recordCounter) add(ctx context.Context, severity log.Severity) {
+	c.once.Do(func() {
+		var err error
+		c.counter, err = c.meter.Int64Counter("log.records",
+			metric.WithDescription("The number of log records emitted, by severity."),
+			metric.WithUnit("{record}"),
+		)
+		if err != nil {
+			// The meter still returns a usable counter alongside the error.
+			otel.Handle(err)
+		}
+	})
+
+	c.counter.Add(ctx, 1, metric.WithAttributes(attribute.String("severity", severity.String())))
+}
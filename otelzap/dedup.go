@@ -0,0 +1,96 @@
+package otelzap
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultDedupCacheSize is the number of entries a DedupCache holds if no
+// positive size is given.
+const defaultDedupCacheSize = 1024
+
+// DedupCache collapses identical records sent to OTel within a time window
+// into a single record. Records are identical if they share the same level and
+// message. The first record is emitted, all identical records within the
+// window are suppressed, and the next record emitted after the window expired
+// carries the number of suppressed records in the log.duplicates attribute.
+//
+// A DedupCache is safe for concurrent use and can be shared across loggers
+// with WithDedupCache, so that identical records logged at the same time from
+// many goroutines collapse into one.
+//
+// The cache holds at most size entries, each one retaining the message of the
+// record, so its memory is bounded by size times the length of the logged
+// messages. Once full, the least recently emitted entry is evicted, losing
+// its count of suppressed records.
+type DedupCache struct {
+	mu      sync.Mutex
+	window  time.Duration
+	size    int
+	entries map[dedupKey]*list.Element
+	// order holds the entries from least to most recently emitted.
+	order *list.List
+}
+
+type dedupKey struct {
+	lvl zapcore.Level
+	msg string
+}
+
+type dedupEntry struct {
+	key        dedupKey
+	emitted    time.Time
+	suppressed int64
+}
+
+// NewDedupCache creates a DedupCache suppressing identical records within the
+// window and holding at most size entries. If size is not positive, a default
+// of 1024 entries is used.
+func NewDedupCache(window time.Duration, size int) *DedupCache {
+	if size <= 0 {
+		size = defaultDedupCacheSize
+	}
+
+	return &DedupCache{
+		window:  window,
+		size:    size,
+		entries: make(map[dedupKey]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// allow reports whether the record should be emitted and, if so, how many
+// identical records were suppressed since the previous one was emitted.
+func (c *DedupCache) allow(lvl zapcore.Level, msg string) (bool, int64) {
+	key := dedupKey{lvl: lvl, msg: msg}
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*dedupEntry)
+		if now.Sub(entry.emitted) < c.window {
+			entry.suppressed++
+			return false, 0
+		}
+
+		suppressed := entry.suppressed
+		entry.emitted = now
+		entry.suppressed = 0
+		c.order.MoveToBack(elem)
+		return true, suppressed
+	}
+
+	if c.order.Len() >= c.size {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*dedupEntry).key)
+	}
+
+	c.entries[key] = c.order.PushBack(&dedupEntry{key: key, emitted: now})
+	return true, 0
+}
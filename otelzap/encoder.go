@@ -0,0 +1,322 @@
+package otelzap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	"go.uber.org/zap/zapcore"
+)
+
+// objectEncoder implements zapcore.ObjectEncoder, converting the fields of
+// a zapcore.ObjectMarshaler to OTel key-values, so they are emitted as a map.
+type objectEncoder struct {
+	kvs []log.KeyValue
+
+	// ns holds the fields added after OpenNamespace, nested under nsKey.
+	ns    *objectEncoder
+	nsKey string
+}
+
+var _ zapcore.ObjectEncoder = (*objectEncoder)(nil)
+
+// objectValue returns the fields of obj as map value. If marshaling the object
+// fails, the fields added until then are returned along with the error.
+func objectValue(obj zapcore.ObjectMarshaler) (log.Value, error) {
+	enc := &objectEncoder{}
+	err := obj.MarshalLogObject(enc)
+	return log.MapValue(enc.keyValues()...), err
+}
+
+// arrayValue returns the elements of arr as slice value. If marshaling the
+// array fails, the elements added until then are returned along with the error.
+func arrayValue(arr zapcore.ArrayMarshaler) (log.Value, error) {
+	enc := &arrayEncoder{}
+	err := arr.MarshalLogArray(enc)
+	return log.SliceValue(enc.values...), err
+}
+
+// reflectedValue converts v to a map or slice value by its JSON representation,
+// like zap's JSON encoder does for reflected fields. It returns the string
+// representation of v if it can't be marshaled.
+func reflectedValue(v interface{}) log.Value {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return log.StringValue(fmt.Sprint(v))
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return log.StringValue(string(b))
+	}
+	return jsonValue(decoded)
+}
+
+// jsonValue converts a value decoded from JSON with json.Decoder.UseNumber.
+func jsonValue(v interface{}) log.Value {
+	switch v := v.(type) {
+	case nil:
+		return log.Value{}
+	case bool:
+		return log.BoolValue(v)
+	case string:
+		return log.StringValue(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return log.Int64Value(i)
+		}
+		if f, err := v.Float64(); err == nil {
+			return log.Float64Value(f)
+		}
+		return log.StringValue(v.String())
+	case []interface{}:
+		values := make([]log.Value, len(v))
+		for i, elem := range v {
+			values[i] = jsonValue(elem)
+		}
+		return log.SliceValue(values...)
+	case map[string]interface{}:
+		kvs := make([]log.KeyValue, 0, len(v))
+		for key, elem := range v {
+			kvs = append(kvs, log.KeyValue{Key: key, Value: jsonValue(elem)})
+		}
+		return log.MapValue(kvs...)
+	default:
+		return log.StringValue(fmt.Sprint(v))
+	}
+}
+
+// keyValues returns the added fields, with the namespaces nested as maps.
+func (e *objectEncoder) keyValues() []log.KeyValue {
+	if e.ns == nil {
+		return e.kvs
+	}
+	return append(e.kvs, log.Map(e.nsKey, e.ns.keyValues()...))
+}
+
+// add adds the field to the innermost open namespace.
+func (e *objectEncoder) add(kv log.KeyValue) {
+	for e.ns != nil {
+		e = e.ns
+	}
+	e.kvs = append(e.kvs, kv)
+}
+
+func (e *objectEncoder) AddArray(key string, v zapcore.ArrayMarshaler) error {
+	value, err := arrayValue(v)
+	e.add(log.KeyValue{Key: key, Value: value})
+	return err
+}
+
+func (e *objectEncoder) AddObject(key string, v zapcore.ObjectMarshaler) error {
+	value, err := objectValue(v)
+	e.add(log.KeyValue{Key: key, Value: value})
+	return err
+}
+
+func (e *objectEncoder) AddBinary(key string, v []byte) {
+	e.add(log.Bytes(key, v))
+}
+
+func (e *objectEncoder) AddByteString(key string, v []byte) {
+	e.add(log.Bytes(key, v))
+}
+
+func (e *objectEncoder) AddBool(key string, v bool) {
+	e.add(log.Bool(key, v))
+}
+
+func (e *objectEncoder) AddComplex128(key string, v complex128) {
+	e.add(log.String(key, strconv.FormatComplex(v, 'E', -1, 128)))
+}
+
+func (e *objectEncoder) AddComplex64(key string, v complex64) {
+	e.add(log.String(key, strconv.FormatComplex(complex128(v), 'E', -1, 64)))
+}
+
+func (e *objectEncoder) AddDuration(key string, v time.Duration) {
+	e.add(log.Int64(key, int64(v)))
+}
+
+func (e *objectEncoder) AddFloat64(key string, v float64) {
+	e.add(log.Float64(key, v))
+}
+
+func (e *objectEncoder) AddFloat32(key string, v float32) {
+	e.add(log.Float64(key, float64(v)))
+}
+
+func (e *objectEncoder) AddInt(key string, v int) {
+	e.add(log.Int(key, v))
+}
+
+func (e *objectEncoder) AddInt64(key string, v int64) {
+	e.add(log.Int64(key, v))
+}
+
+func (e *objectEncoder) AddInt32(key string, v int32) {
+	e.add(log.Int64(key, int64(v)))
+}
+
+func (e *objectEncoder) AddInt16(key string, v int16) {
+	e.add(log.Int64(key, int64(v)))
+}
+
+func (e *objectEncoder) AddInt8(key string, v int8) {
+	e.add(log.Int64(key, int64(v)))
+}
+
+func (e *objectEncoder) AddString(key, v string) {
+	e.add(log.String(key, v))
+}
+
+func (e *objectEncoder) AddTime(key string, v time.Time) {
+	e.add(log.Int64(key, v.UnixNano()))
+}
+
+func (e *objectEncoder) AddUint(key string, v uint) {
+	e.add(log.Int64(key, int64(v)))
+}
+
+func (e *objectEncoder) AddUint64(key string, v uint64) {
+	e.add(log.Int64(key, int64(v)))
+}
+
+func (e *objectEncoder) AddUint32(key string, v uint32) {
+	e.add(log.Int64(key, int64(v)))
+}
+
+func (e *objectEncoder) AddUint16(key string, v uint16) {
+	e.add(log.Int64(key, int64(v)))
+}
+
+func (e *objectEncoder) AddUint8(key string, v uint8) {
+	e.add(log.Int64(key, int64(v)))
+}
+
+func (e *objectEncoder) AddUintptr(key string, v uintptr) {
+	e.add(log.Int64(key, int64(v)))
+}
+
+func (e *objectEncoder) AddReflected(key string, v interface{}) error {
+	e.add(log.KeyValue{Key: key, Value: reflectedValue(v)})
+	return nil
+}
+
+func (e *objectEncoder) OpenNamespace(key string) {
+	for e.ns != nil {
+		e = e.ns
+	}
+	e.ns = &objectEncoder{}
+	e.nsKey = key
+}
+
+// arrayEncoder implements zapcore.ArrayEncoder, converting the elements of a
+// zapcore.ArrayMarshaler to OTel values, so they are emitted as a slice.
+type arrayEncoder struct {
+	values []log.Value
+}
+
+var _ zapcore.ArrayEncoder = (*arrayEncoder)(nil)
+
+func (e *arrayEncoder) AppendArray(v zapcore.ArrayMarshaler) error {
+	value, err := arrayValue(v)
+	e.values = append(e.values, value)
+	return err
+}
+
+func (e *arrayEncoder) AppendObject(v zapcore.ObjectMarshaler) error {
+	value, err := objectValue(v)
+	e.values = append(e.values, value)
+	return err
+}
+
+func (e *arrayEncoder) AppendReflected(v interface{}) error {
+	e.values = append(e.values, reflectedValue(v))
+	return nil
+}
+
+func (e *arrayEncoder) AppendBool(v bool) {
+	e.values = append(e.values, log.BoolValue(v))
+}
+
+func (e *arrayEncoder) AppendByteString(v []byte) {
+	e.values = append(e.values, log.BytesValue(v))
+}
+
+func (e *arrayEncoder) AppendComplex128(v complex128) {
+	e.values = append(e.values, log.StringValue(strconv.FormatComplex(v, 'E', -1, 128)))
+}
+
+func (e *arrayEncoder) AppendComplex64(v complex64) {
+	e.values = append(e.values, log.StringValue(strconv.FormatComplex(complex128(v), 'E', -1, 64)))
+}
+
+func (e *arrayEncoder) AppendDuration(v time.Duration) {
+	e.values = append(e.values, log.Int64Value(int64(v)))
+}
+
+func (e *arrayEncoder) AppendFloat64(v float64) {
+	e.values = append(e.values, log.Float64Value(v))
+}
+
+func (e *arrayEncoder) AppendFloat32(v float32) {
+	e.values = append(e.values, log.Float64Value(float64(v)))
+}
+
+func (e *arrayEncoder) AppendInt(v int) {
+	e.values = append(e.values, log.IntValue(v))
+}
+
+func (e *arrayEncoder) AppendInt64(v int64) {
+	e.values = append(e.values, log.Int64Value(v))
+}
+
+func (e *arrayEncoder) AppendInt32(v int32) {
+	e.values = append(e.values, log.Int64Value(int64(v)))
+}
+
+func (e *arrayEncoder) AppendInt16(v int16) {
+	e.values = append(e.values, log.Int64Value(int64(v)))
+}
+
+func (e *arrayEncoder) AppendInt8(v int8) {
+	e.values = append(e.values, log.Int64Value(int64(v)))
+}
+
+func (e *arrayEncoder) AppendString(v string) {
+	e.values = append(e.values, log.StringValue(v))
+}
+
+func (e *arrayEncoder) AppendTime(v time.Time) {
+	e.values = append(e.values, log.Int64Value(v.UnixNano()))
+}
+
+func (e *arrayEncoder) AppendUint(v uint) {
+	e.values = append(e.values, log.Int64Value(int64(v)))
+}
+
+func (e *arrayEncoder) AppendUint64(v uint64) {
+	e.values = append(e.values, log.Int64Value(int64(v)))
+}
+
+func (e *arrayEncoder) AppendUint32(v uint32) {
+	e.values = append(e.values, log.Int64Value(int64(v)))
+}
+
+func (e *arrayEncoder) AppendUint16(v uint16) {
+	e.values = append(e.values, log.Int64Value(int64(v)))
+}
+
+func (e *arrayEncoder) AppendUint8(v uint8) {
+	e.values = append(e.values, log.Int64Value(int64(v)))
+}
+
+func (e *arrayEncoder) AppendUintptr(v uintptr) {
+	e.values = append(e.values, log.Int64Value(int64(v)))
+}
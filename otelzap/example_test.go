@@ -1,13 +1,16 @@
 package otelzap_test
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/sierrasoftworks/humane-errors-go"
 	"github.com/spechtlabs/go-otel-utils/otelprovider"
 	"github.com/spechtlabs/go-otel-utils/otelzap"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func ExampleNew() {
@@ -19,9 +22,12 @@ func ExampleNew() {
 	}
 
 	// Initialize otel logging provider
-	logProvider := otelprovider.NewLogger(
+	logProvider, err := otelprovider.NewLogger(
 		otelprovider.WithLogAutomaticEnv(),
 	)
+	if err != nil {
+		fmt.Printf("failed to initialize log exporters: %v", err)
+	}
 
 	// Create otelZap Logger
 	otelZapLogger := otelzap.New(zapLogger,
@@ -43,3 +49,20 @@ func ExampleNew() {
 		zap.String("foo", "bar"),
 	)
 }
+
+func ExampleWithFieldRedactor() {
+	logger := otelzap.New(zap.NewExample(),
+		otelzap.WithFieldRedactor(func(field zapcore.Field) zapcore.Field {
+			if strings.Contains(strings.ToLower(field.Key), "password") {
+				return zap.String(field.Key, "[REDACTED]")
+			}
+			return field
+		}),
+	)
+
+	logger.Ctx(context.Background()).Info("user created",
+		zap.String("user", "alice"),
+		zap.String("password", "hunter2"),
+	)
+	// Output: {"level":"info","msg":"user created","user":"alice","password":"[REDACTED]"}
+}
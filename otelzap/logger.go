@@ -4,62 +4,203 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/aws/smithy-go/logging"
+	pkgerrors "github.com/pkg/errors"
 	"github.com/sierrasoftworks/humane-errors-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/log"
 	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/log/noop"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+const (
+	errorKey       = "error"
+	errorAdviceKey = "error_advice"
+	errorCausesKey = "error_causes"
+	errorChainKey  = "error_chain"
+	errorStackKey  = "error_stack"
+)
+
+// maxPendingEmits bounds the number of emits that still run in the background
+// after exceeding the timeout set with WithEmitTimeout.
+const maxPendingEmits = 64
+
+// stackTracer is implemented by errors carrying the stack trace of where they
+// were created, e.g. by github.com/pkg/errors.
+type stackTracer interface {
+	StackTrace() pkgerrors.StackTrace
+}
+
 // Logger is a thin wrapper for zap.Logger that adds Ctx method.
 type Logger struct {
 	*zap.Logger
-	skipCaller *zap.Logger
+	// writer is the zap logger entries are checked with by LoggerWithCtx.write,
+	// skipping write and the exported logging method calling it.
+	writer *zap.Logger
 
 	provider   log.LoggerProvider
 	version    string
 	schemaURL  string
+	scopeAttrs []attribute.KeyValue
 	otelLogger log.Logger
+	// severityRouting maps levels to the scope names of the OTel loggers in
+	// routedLoggers, see WithSeverityRouting.
+	severityRouting map[zapcore.Level]string
+	routedLoggers   map[zapcore.Level]log.Logger
 
-	minLevel         zapcore.Level
+	minLevel         zap.AtomicLevel
+	zapLevel         zap.AtomicLevel
 	errorStatusLevel zapcore.Level
 	minAnnotateLevel zapcore.Level
-
-	caller     bool
-	stackTrace bool
-
-	// extraFields contains a number of zap.Fields that are added to every log entry
+	errorDetailLevel zapcore.Level
+	severityMapper   func(zapcore.Level) log.Severity
+
+	caller              bool
+	callerFunction      bool
+	callerFile          bool
+	callerLine          bool
+	codeFunctionKey     string
+	codeFilepathKey     string
+	codeLinenoKey       string
+	errorKey            string
+	errorAdviceKey      string
+	errorCausesKey      string
+	stackTrace          bool
+	stringifyAttributes bool
+	redactor            func(zapcore.Field) zapcore.Field
+	spanAttributeMaxLen int
+	spanEvents          bool
+	spanHook            SpanHook
+	templateField       bool
+	goroutineID         bool
+
+	// extraFields contains a number of zap.Fields that are added to every log
+	// entry. It is never modified in place, so that clones may share it.
 	extraFields []zap.Field
-	// extraFieldsOnce contains a number of zap.Fields that are added to only the next log entry
-	extraFieldsOnce []zap.Field
-	callerDepth     int
+	// coreFields contains the fields added to the zap core with zap.Fields,
+	// which are only added to the OTel record, as zap already writes them. Like
+	// extraFields, it is never modified in place.
+	coreFields    []zap.Field
+	zapOptions    []zap.Option
+	callerDepth   int
+	packageCaller *packageCaller
+
+	attributeRanks    [numAttributeSources]int
+	contextAttributes bool
+	contextExtractors []func(context.Context) []zapcore.Field
+	traceparentKey    string
+	traceIDFields     bool
+	traceCorrelation  bool
+	baggageFields     bool
+	baggageKeys       []string
+
+	emitOnlyWithinSpan bool
+	emitAnnotated      bool
+	otelSampler        OtelSampler
+
+	clock         func() time.Time
+	observedClock func() time.Time
+	emitTimeout   time.Duration
+	emitSlots     chan struct{}
+	droppedEmits  *atomic.Uint64
+
+	dedup *DedupCache
+
+	spanBuffer *spanLogBuffer
+
+	meterProvider metric.MeterProvider
+	counters      *counters
+	recordCounter *recordCounter
 }
 
 // New creates a new Logger instance with specified options and returns it along
 // with an undo function used for cleanup.
 func New(logger *zap.Logger, opts ...Option) *Logger {
+	zapLevel := zap.NewAtomicLevelAt(zapcore.DebugLevel)
 	l := &Logger{
-		Logger:     logger,
-		skipCaller: logger.WithOptions(zap.AddCallerSkip(1)),
+		Logger: logger,
+		writer: newWriter(logger, zapLevel, 0),
 
 		provider: global.GetLoggerProvider(),
 
-		minLevel:         zap.InfoLevel,
+		minLevel:         zap.NewAtomicLevelAt(zap.InfoLevel),
+		zapLevel:         zapLevel,
 		errorStatusLevel: zap.ErrorLevel,
 		minAnnotateLevel: zap.WarnLevel,
+		errorDetailLevel: zap.DebugLevel,
 		caller:           true,
+		callerFunction:   true,
+		callerFile:       true,
+		callerLine:       true,
+		codeFunctionKey:  codeFunctionKey,
+		codeFilepathKey:  codeFilepathKey,
+		codeLinenoKey:    codeLinenoKey,
+		errorKey:         errorKey,
+		errorAdviceKey:   errorAdviceKey,
+		errorCausesKey:   errorCausesKey,
 		callerDepth:      0,
+		attributeRanks:   defaultAttributeRanks,
+		severityMapper:   ConvertLevel,
+
+		emitAnnotated: true,
+		clock:         time.Now,
+		droppedEmits:  &atomic.Uint64{},
 	}
 	for _, opt := range opts {
 		opt(l)
 	}
+	if len(l.zapOptions) > 0 {
+		l = l.WithOptions(l.zapOptions...)
+	}
 	l.otelLogger = l.newOtelLogger(logger.Name())
+	if len(l.severityRouting) > 0 {
+		l.routedLoggers = make(map[zapcore.Level]log.Logger, len(l.severityRouting))
+		for lvl, name := range l.severityRouting {
+			l.routedLoggers[lvl] = l.newOtelLogger(name)
+		}
+	}
+	if l.meterProvider != nil {
+		l.counters = newCounters(l.newMeter(logger.Name()))
+	}
 
 	return l
 }
 
+// newWriter returns the writer of a Logger wrapping the given zap logger,
+// skipping the frames of otelzap and the caller depth and filtering the
+// entries by the zap level.
+func newWriter(logger *zap.Logger, zapLevel zap.AtomicLevel, callerDepth int) *zap.Logger {
+	return logger.WithOptions(
+		zap.AddCallerSkip(2+callerDepth),
+		zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &levelCore{Core: core, level: zapLevel}
+		}),
+	)
+}
+
+// NewNop returns a Logger that doesn't write any logs, emit any OTel records
+// or annotate any spans. Install it with ReplaceGlobals to silence libraries
+// logging through the global Logger, e.g. in tests.
+func NewNop() *Logger {
+	return New(zap.NewNop(),
+		WithLoggerProvider(noop.NewLoggerProvider()),
+		WithMinLevel(zapcore.InvalidLevel),
+		WithAnnotateLevel(zapcore.InvalidLevel),
+		WithErrorStatusLevel(zapcore.InvalidLevel),
+		WithCaller(false),
+	)
+}
+
 func (l *Logger) newOtelLogger(name string) log.Logger {
 	var opts []log.LoggerOption
 	if l.version != "" {
@@ -68,9 +209,176 @@ func (l *Logger) newOtelLogger(name string) log.Logger {
 	if l.schemaURL != "" {
 		opts = append(opts, log.WithSchemaURL(l.schemaURL))
 	}
+	if len(l.scopeAttrs) > 0 {
+		opts = append(opts, log.WithInstrumentationAttributes(l.scopeAttrs...))
+	}
 	return l.provider.Logger(name, opts...)
 }
 
+func (l *Logger) newMeter(name string) metric.Meter {
+	var opts []metric.MeterOption
+	if l.version != "" {
+		opts = append(opts, metric.WithInstrumentationVersion(l.version))
+	}
+	if l.schemaURL != "" {
+		opts = append(opts, metric.WithSchemaURL(l.schemaURL))
+	}
+	if len(l.scopeAttrs) > 0 {
+		opts = append(opts, metric.WithInstrumentationAttributes(l.scopeAttrs...))
+	}
+	return l.meterProvider.Meter(name, opts...)
+}
+
+// emit sends the record to the OTel logger, unless it is held back until the
+// end of its span by WithDeferLogExportUntilSpanEnd. Records logged with an
+// already canceled context are emitted nevertheless.
+//
+// If an emit timeout is configured, the record is dropped once the timeout
+// expires, so a slow (e.g. synchronous) processor can't block the caller for
+// longer than that.
+func (l *Logger) emit(ctx context.Context, lvl zapcore.Level, record log.Record) {
+	// A canceled context, e.g. at the end of a request, would cause processors
+	// and exporters to drop the record. Emit it with the values of the context,
+	// including the span, but without its cancellation.
+	if ctx.Err() != nil {
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	otelLogger := l.otelLoggerFor(lvl)
+	if l.spanBuffer != nil && l.spanBuffer.add(ctx, record, func(ctx context.Context, record log.Record) {
+		l.emitNow(ctx, otelLogger, record)
+	}) {
+		return
+	}
+
+	l.emitNow(ctx, otelLogger, record)
+}
+
+// otelLoggerFor returns the OTel logger the records at the given level are
+// emitted to, see WithSeverityRouting.
+func (l *Logger) otelLoggerFor(lvl zapcore.Level) log.Logger {
+	if otelLogger, ok := l.routedLoggers[lvl]; ok {
+		return otelLogger
+	}
+	return l.otelLogger
+}
+
+func (l *Logger) emitNow(ctx context.Context, otelLogger log.Logger, record log.Record) {
+	if l.emitTimeout <= 0 {
+		otelLogger.Emit(ctx, record)
+		return
+	}
+
+	select {
+	case l.emitSlots <- struct{}{}:
+	default:
+		// All slots are taken by emits that are stuck, so don't pile up more.
+		l.droppedEmits.Add(1)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.emitTimeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer func() { <-l.emitSlots }()
+		defer close(done)
+		otelLogger.Emit(ctx, record)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		l.droppedEmits.Add(1)
+	}
+}
+
+// entryCaller returns the caller reported to both zap and OTel, skipping the
+// given number of frames unless the wrapper packages are skipped with
+// WithCallerSkipPackages.
+func (l *Logger) entryCaller(skip int) zapcore.EntryCaller {
+	var caller zapcore.EntryCaller
+	if l.packageCaller != nil {
+		caller.Function, caller.File, caller.Line, caller.Defined = l.packageCaller.caller(skip + 1)
+	} else {
+		caller.Function, caller.File, caller.Line, caller.Defined = runtimeCaller(skip + 1 + l.callerDepth)
+	}
+	return caller
+}
+
+// SpanProcessor returns the span processor that releases the records held back
+// by WithDeferLogExportUntilSpanEnd. It has to be registered with the tracer
+// provider, e.g. with trace.WithSpanProcessor. It returns nil if the option is
+// not set.
+func (l *Logger) SpanProcessor() sdktrace.SpanProcessor {
+	if l.spanBuffer == nil {
+		return nil
+	}
+	return l.spanBuffer
+}
+
+// MinLevel returns the minimal zap logging level on which log messages are
+// recorded on the span and emitted to OTel.
+func (l *Logger) MinLevel() zapcore.Level {
+	return l.minLevel.Level()
+}
+
+// SetMinLevel changes the minimal zap logging level on which log messages are
+// recorded on the span and emitted to OTel at runtime, e.g. to temporarily
+// enable debug logging. It's safe to use concurrently and affects all Loggers
+// derived from this one with With, WithOptions or Sugar, and vice versa, but
+// not the ones created with Clone.
+//
+// It does not change which messages are written to zap, see SetZapLevel.
+func (l *Logger) SetMinLevel(lvl zapcore.Level) {
+	l.minLevel.SetLevel(lvl)
+}
+
+// ZapLevel returns the minimal level on which log messages are written to
+// zap, in addition to the level of the wrapped zap core.
+func (l *Logger) ZapLevel() zapcore.Level {
+	return l.zapLevel.Level()
+}
+
+// SetZapLevel changes the minimal level on which log messages are written to
+// zap at runtime, independently of the level on which they are emitted to OTel
+// (see SetMinLevel). It's safe to use concurrently and affects all Loggers
+// derived from this one with With, WithOptions or Sugar, and vice versa, but
+// not the ones created with Clone.
+//
+// The level of the wrapped zap core still applies, so messages below it are
+// not written even if they are enabled by this level.
+func (l *Logger) SetZapLevel(lvl zapcore.Level) {
+	l.zapLevel.SetLevel(lvl)
+}
+
+// DroppedEmits returns the number of records whose emit exceeded the timeout
+// configured with WithEmitTimeout, or that were dropped because too many
+// emits were still pending.
+func (l *Logger) DroppedEmits() uint64 {
+	return l.droppedEmits.Load()
+}
+
+// Sync flushes the buffered log entries of the zap logger and, if the
+// LoggerProvider configured with WithLoggerProvider supports it, e.g. the
+// log.LoggerProvider of the OTel SDK, the records buffered by its processors.
+// The global LoggerProvider only delegates and can't be flushed through the
+// Logger; flush the provider registered globally instead.
+//
+// Applications should take care to call Sync before exiting.
+func (l *Logger) Sync() error {
+	err := l.Logger.Sync()
+
+	if flusher, ok := l.provider.(interface{ ForceFlush(context.Context) error }); ok {
+		if flushErr := flusher.ForceFlush(context.Background()); flushErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to flush OTel logs: %w", flushErr))
+		}
+	}
+
+	return err
+}
+
 // WithOptions clones the current Logger, applies the supplied Options,
 // and returns the resulting Logger. It's safe to use concurrently.
 func (l *Logger) WithOptions(opts ...zap.Option) *Logger {
@@ -79,12 +387,33 @@ func (l *Logger) WithOptions(opts ...zap.Option) *Logger {
 	zap.New(&fieldExtractorCore{extraFields: &extraFields}, opts...)
 	clone := *l
 	clone.Logger = l.Logger.WithOptions(opts...)
-	clone.skipCaller = l.skipCaller.WithOptions(opts...)
-	clone.extraFields = append(clone.extraFields, extraFields...)
+	clone.writer = l.writer.WithOptions(opts...)
+	clone.coreFields = append(l.coreFields[:len(l.coreFields):len(l.coreFields)], extraFields...)
 	return &clone
 }
 
-// WithError adds a humane.Error to the logging context.
+// Named adds a new path segment to the logger's name, see zap.Logger.Named.
+// The OTel records of the returned Logger are emitted with the new name as
+// instrumentation scope, except for the levels routed with
+// WithSeverityRouting.
+func (l *Logger) Named(name string) *Logger {
+	clone := *l
+	clone.Logger = l.Logger.Named(name)
+	clone.writer = l.writer.Named(name)
+	clone.otelLogger = clone.newOtelLogger(clone.Logger.Name())
+	return &clone
+}
+
+// WithError adds an error to the logging context.
+//
+// The messages of the errors wrapped by err, e.g. with fmt.Errorf and %w, are
+// added as error_chain, and the stack trace of the innermost error carrying
+// one, e.g. created with github.com/pkg/errors, as error_stack. For a
+// humane.Error, its advice and causes are added as well.
+//
+// The chain, stack trace, advice and causes of the error are only attached to
+// the OTel record if the entry is logged at or above the level configured with
+// WithErrorDetailLevel.
 //
 // For example,
 //
@@ -92,11 +421,18 @@ func (l *Logger) WithOptions(opts ...zap.Option) *Logger {
 //	    humane.New("foo", "bar")
 //		)
 func (l *Logger) WithError(err error) *Logger {
+	return l.With(l.errorFields(err)...)
+}
+
+// errorFields returns the fields added by WithError.
+func (l *Logger) errorFields(err error) []zap.Field {
 	zapFields := make([]zap.Field, 0)
-	zapFields = append(zapFields, zap.Error(err))
+	zapFields = append(zapFields, zap.NamedError(l.errorKey, err))
 
 	advice := make([]string, 0)
 	causes := make([]error, 0)
+	chain := make([]string, 0)
+	var stack string
 	for err != nil {
 		var herr humane.Error
 		if ok := errors.As(err, &herr); ok {
@@ -104,23 +440,42 @@ func (l *Logger) WithError(err error) *Logger {
 			advice = append(advice, herr.Advice()...)
 		}
 
+		if st, ok := err.(stackTracer); ok {
+			stack = strings.TrimPrefix(fmt.Sprintf("%+v", st.StackTrace()), "\n")
+		}
+
 		err = errors.Unwrap(err)
+		if err != nil {
+			chain = append(chain, err.Error())
+		}
 	}
 
 	if len(advice) > 0 {
-		zapFields = append(zapFields, zap.Strings("error_advice", advice))
+		zapFields = append(zapFields, zap.Strings(l.errorAdviceKey, advice))
 	}
 
 	if len(causes) > 1 {
-		zapFields = append(zapFields, zap.Errors("error_causes", causes[1:]))
+		zapFields = append(zapFields, zap.Errors(l.errorCausesKey, causes[1:]))
+	}
+
+	if len(chain) > 0 {
+		zapFields = append(zapFields, zap.Strings(errorChainKey, chain))
 	}
 
-	return l.With(zapFields...)
+	if stack != "" {
+		zapFields = append(zapFields, zap.String(errorStackKey, stack))
+	}
+
+	return zapFields
 }
 
+// With clones the current Logger and adds the given fields to every log entry
+// of the clone. The Logger itself is not modified, so it's safe to use
+// concurrently.
 func (l *Logger) With(fields ...zap.Field) *Logger {
-	l.extraFieldsOnce = append(l.extraFieldsOnce, fields...)
-	return l
+	clone := *l
+	clone.extraFields = append(l.extraFields[:len(l.extraFields):len(l.extraFields)], fields...)
+	return &clone
 }
 
 // Sugar wraps the Logger to provide a more ergonomic, but slightly slower,
@@ -130,14 +485,21 @@ func (l *Logger) With(fields ...zap.Field) *Logger {
 func (l *Logger) Sugar() *SugaredLogger {
 	return &SugaredLogger{
 		SugaredLogger: l.Logger.Sugar(),
-		skipCaller:    l.skipCaller.Sugar(),
 		l:             l,
 	}
 }
 
-// Clone clones the current logger applying the supplied options.
+// Clone clones the current logger applying the supplied options. Unlike the
+// Loggers derived with With, WithOptions or Sugar, the clone has its own min
+// and zap levels, so WithMinLevel, WithZapLevel, SetMinLevel and SetZapLevel
+// on the clone don't change the levels of this Logger.
 func (l *Logger) Clone(opts ...Option) *Logger {
 	clone := *l
+	clone.minLevel = zap.NewAtomicLevelAt(l.minLevel.Level())
+	// The writer filters by the zap level, so it has to be rebuilt for the
+	// clone's own level.
+	clone.zapLevel = zap.NewAtomicLevelAt(l.zapLevel.Level())
+	clone.writer = newWriter(l.Logger, clone.zapLevel, l.callerDepth)
 	for _, opt := range opts {
 		opt(&clone)
 	}
@@ -157,36 +519,31 @@ func (l *Logger) Ctx(ctx context.Context) LoggerWithCtx {
 // Any Fields that require  evaluation (such as Objects) are evaluated upon
 // invocation of Log.
 func (l *Logger) Log(lvl zapcore.Level, msg string, fields ...zapcore.Field) {
-	fields = l.logFields(fields)
-	l.skipCaller.Log(lvl, msg, fields...)
+	l.Ctx(context.Background()).write(lvl, msg, fields)
 }
 
 // Debug logs a message at DebugLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l *Logger) Debug(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(fields)
-	l.skipCaller.Debug(msg, fields...)
+	l.Ctx(context.Background()).write(zap.DebugLevel, msg, fields)
 }
 
 // Info logs a message at InfoLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l *Logger) Info(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(fields)
-	l.skipCaller.Info(msg, fields...)
+	l.Ctx(context.Background()).write(zap.InfoLevel, msg, fields)
 }
 
 // Warn logs a message at WarnLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l *Logger) Warn(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(fields)
-	l.skipCaller.Warn(msg, fields...)
+	l.Ctx(context.Background()).write(zap.WarnLevel, msg, fields)
 }
 
 // Error logs a message at ErrorLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l *Logger) Error(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(fields)
-	l.skipCaller.Error(msg, fields...)
+	l.Ctx(context.Background()).write(zap.ErrorLevel, msg, fields)
 }
 
 // DPanic logs a message at DPanicLevel. The message includes any fields
@@ -196,8 +553,7 @@ func (l *Logger) Error(msg string, fields ...zapcore.Field) {
 // "development panic"). This is useful for catching errors that are
 // recoverable, but shouldn't ever happen.
 func (l *Logger) DPanic(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(fields)
-	l.skipCaller.DPanic(msg, fields...)
+	l.Ctx(context.Background()).write(zap.DPanicLevel, msg, fields)
 }
 
 // Panic logs a message at PanicLevel. The message includes any fields passed
@@ -205,8 +561,7 @@ func (l *Logger) DPanic(msg string, fields ...zapcore.Field) {
 //
 // The logger then panics, even if logging at PanicLevel is disabled.
 func (l *Logger) Panic(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(fields)
-	l.skipCaller.Panic(msg, fields...)
+	l.Ctx(context.Background()).write(zap.PanicLevel, msg, fields)
 }
 
 // Fatal logs a message at FatalLevel. The message includes any fields passed
@@ -215,67 +570,140 @@ func (l *Logger) Panic(msg string, fields ...zapcore.Field) {
 // The logger then calls os.Exit(1), even if logging at FatalLevel is
 // disabled.
 func (l *Logger) Fatal(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(fields)
-	l.skipCaller.Fatal(msg, fields...)
+	l.Ctx(context.Background()).write(zap.FatalLevel, msg, fields)
 }
 
+// LogContext logs a message at the specified level with the context, like
+// Ctx(ctx).Log. The message includes any fields passed at the log site, as
+// well as any fields accumulated on the logger.
 func (l *Logger) LogContext(ctx context.Context, lvl zapcore.Level, msg string, fields ...zapcore.Field) {
-	fields = l.logFields(fields)
-	l.Ctx(ctx).l.skipCaller.Log(lvl, msg, fields...)
+	l.Ctx(ctx).write(lvl, msg, fields)
 }
 
+// DebugContext logs a message at DebugLevel with the context, like
+// Ctx(ctx).Debug.
 func (l *Logger) DebugContext(ctx context.Context, msg string, fields ...zapcore.Field) {
-	l.Ctx(ctx).l.skipCaller.Debug(msg, fields...)
+	l.Ctx(ctx).write(zap.DebugLevel, msg, fields)
 }
 
+// InfoContext logs a message at InfoLevel with the context, like
+// Ctx(ctx).Info.
 func (l *Logger) InfoContext(ctx context.Context, msg string, fields ...zapcore.Field) {
-	l.Ctx(ctx).l.skipCaller.Info(msg, fields...)
+	l.Ctx(ctx).write(zap.InfoLevel, msg, fields)
 }
 
+// WarnContext logs a message at WarnLevel with the context, like
+// Ctx(ctx).Warn.
 func (l *Logger) WarnContext(ctx context.Context, msg string, fields ...zapcore.Field) {
-	l.Ctx(ctx).l.skipCaller.Warn(msg, fields...)
+	l.Ctx(ctx).write(zap.WarnLevel, msg, fields)
 }
 
+// ErrorContext logs a message at ErrorLevel with the context, like
+// Ctx(ctx).Error.
 func (l *Logger) ErrorContext(ctx context.Context, msg string, fields ...zapcore.Field) {
-	l.Ctx(ctx).l.skipCaller.Error(msg, fields...)
+	l.Ctx(ctx).write(zap.ErrorLevel, msg, fields)
 }
 
+// DPanicContext logs a message at DPanicLevel with the context, like
+// Ctx(ctx).DPanic.
 func (l *Logger) DPanicContext(ctx context.Context, msg string, fields ...zapcore.Field) {
-	l.Ctx(ctx).l.skipCaller.DPanic(msg, fields...)
+	l.Ctx(ctx).write(zap.DPanicLevel, msg, fields)
 }
 
+// PanicContext logs a message at PanicLevel with the context, like
+// Ctx(ctx).Panic.
 func (l *Logger) PanicContext(ctx context.Context, msg string, fields ...zapcore.Field) {
-	l.Ctx(ctx).l.skipCaller.Panic(msg, fields...)
+	l.Ctx(ctx).write(zap.PanicLevel, msg, fields)
 }
 
+// FatalContext logs a message at FatalLevel with the context, like
+// Ctx(ctx).Fatal.
 func (l *Logger) FatalContext(ctx context.Context, msg string, fields ...zapcore.Field) {
-	l.Ctx(ctx).l.skipCaller.Fatal(msg, fields...)
+	l.Ctx(ctx).write(zap.FatalLevel, msg, fields)
 }
 
+// Logf implements the logging.Logger interface of smithy-go, the logging
+// abstraction of the AWS SDK for Go v2. See SmithyLogger for an adapter that
+// also passes on the context of the SDK operations.
 func (l *Logger) Logf(classification logging.Classification, format string, fields ...interface{}) {
-	msg := fmt.Sprintf(format, fields...)
+	l.Ctx(context.Background()).write(smithyLevel(classification), fmt.Sprintf(format, fields...), nil)
+}
 
-	switch classification {
-	case logging.Warn:
-		l.skipCaller.Warn(msg)
+// contextFields returns the fields derived from the context, which are added
+// to both the zap output and the OTel record.
+func (l *Logger) contextFields(ctx context.Context) []zapcore.Field {
+	var fields []zapcore.Field
 
-	case logging.Debug:
-		l.skipCaller.Debug(msg)
+	if l.traceparentKey != "" {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			fields = append(fields, zap.String(l.traceparentKey, traceparent(sc)))
+		}
+	}
 
-	default:
-		l.skipCaller.Info(msg)
+	for _, extract := range l.contextExtractors {
+		fields = append(fields, extract(ctx)...)
 	}
+
+	return fields
 }
 
-func (l *Logger) logFields(fields []zapcore.Field) []zapcore.Field {
-	if len(l.extraFields) > 0 {
-		fields = append(fields, l.extraFields...)
+// baggageFieldsFromContext returns the configured members of the baggage in
+// the context as fields, or all of them if no keys are configured.
+func (l *Logger) baggageFieldsFromContext(ctx context.Context) []zapcore.Field {
+	if !l.baggageFields {
+		return nil
 	}
 
-	if len(l.extraFieldsOnce) > 0 {
-		fields = append(fields, l.extraFieldsOnce...)
-		l.extraFieldsOnce = make([]zap.Field, 0)
+	bag := baggage.FromContext(ctx)
+	if bag.Len() == 0 {
+		return nil
+	}
+
+	var fields []zapcore.Field
+	if len(l.baggageKeys) == 0 {
+		for _, member := range bag.Members() {
+			fields = append(fields, zap.String(member.Key(), member.Value()))
+		}
+		return fields
 	}
 
+	for _, key := range l.baggageKeys {
+		if member := bag.Member(key); member.Key() != "" {
+			fields = append(fields, zap.String(key, member.Value()))
+		}
+	}
 	return fields
 }
+
+// traceparent formats the span context as W3C traceparent header value.
+func traceparent(sc trace.SpanContext) string {
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags())
+}
+
+// fieldsError returns the error of the first error field, e.g. added with
+// zap.Error or WithError, or nil if there is none.
+func fieldsError(fields []zapcore.Field) error {
+	for _, field := range fields {
+		if field.Type != zapcore.ErrorType {
+			continue
+		}
+		if err, ok := field.Interface.(error); ok && err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withoutErrorDetails returns the fields without the chain, stack trace,
+// advice and causes added by WithError.
+func (l *Logger) withoutErrorDetails(fields []zapcore.Field) []zapcore.Field {
+	filtered := make([]zapcore.Field, 0, len(fields))
+	for _, field := range fields {
+		switch field.Key {
+		case l.errorAdviceKey, l.errorCausesKey, errorChainKey, errorStackKey:
+			continue
+		}
+		filtered = append(filtered, field)
+	}
+	return filtered
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/log"
 	"go.opentelemetry.io/otel/trace"
@@ -58,32 +60,34 @@ func (l LoggerWithCtx) Clone(opts ...Option) LoggerWithCtx {
 	}
 }
 
+// Log logs a message at the specified level. The message includes any fields
+// passed at the log site, as well as any fields accumulated on the logger.
+func (l LoggerWithCtx) Log(lvl zapcore.Level, msg string, fields ...zapcore.Field) {
+	l.write(lvl, msg, fields)
+}
+
 // Debug logs a message at DebugLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l LoggerWithCtx) Debug(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(l.ctx, zap.DebugLevel, msg, fields)
-	l.l.skipCaller.Debug(msg, fields...)
+	l.write(zap.DebugLevel, msg, fields)
 }
 
 // Info logs a message at InfoLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l LoggerWithCtx) Info(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(l.ctx, zap.InfoLevel, msg, fields)
-	l.l.skipCaller.Info(msg, fields...)
+	l.write(zap.InfoLevel, msg, fields)
 }
 
 // Warn logs a message at WarnLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l LoggerWithCtx) Warn(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(l.ctx, zap.WarnLevel, msg, fields)
-	l.l.skipCaller.Warn(msg, fields...)
+	l.write(zap.WarnLevel, msg, fields)
 }
 
 // Error logs a message at ErrorLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l LoggerWithCtx) Error(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(l.ctx, zap.ErrorLevel, msg, fields)
-	l.l.skipCaller.Error(msg, fields...)
+	l.write(zap.ErrorLevel, msg, fields)
 }
 
 // DPanic logs a message at DPanicLevel. The message includes any fields
@@ -93,8 +97,7 @@ func (l LoggerWithCtx) Error(msg string, fields ...zapcore.Field) {
 // "development panic"). This is useful for catching errors that are
 // recoverable, but shouldn't ever happen.
 func (l LoggerWithCtx) DPanic(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(l.ctx, zap.DPanicLevel, msg, fields)
-	l.l.skipCaller.DPanic(msg, fields...)
+	l.write(zap.DPanicLevel, msg, fields)
 }
 
 // Panic logs a message at PanicLevel. The message includes any fields passed
@@ -102,8 +105,7 @@ func (l LoggerWithCtx) DPanic(msg string, fields ...zapcore.Field) {
 //
 // The logger then panics, even if logging at PanicLevel is disabled.
 func (l LoggerWithCtx) Panic(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(l.ctx, zap.PanicLevel, msg, fields)
-	l.l.skipCaller.Panic(msg, fields...)
+	l.write(zap.PanicLevel, msg, fields)
 }
 
 // Fatal logs a message at FatalLevel. The message includes any fields passed
@@ -112,65 +114,219 @@ func (l LoggerWithCtx) Panic(msg string, fields ...zapcore.Field) {
 // The logger then calls os.Exit(1), even if logging at FatalLevel is
 // disabled.
 func (l LoggerWithCtx) Fatal(msg string, fields ...zapcore.Field) {
-	fields = l.logFields(l.ctx, zap.FatalLevel, msg, fields)
-	l.l.skipCaller.Fatal(msg, fields...)
+	l.write(zap.FatalLevel, msg, fields)
+}
+
+// write writes the entry to zap and emits it to OTel. It has to be called
+// directly by the exported logging method, so the caller is detected once and
+// both zap and OTel report the call site of that method. The otelFields are
+// only added to the OTel record.
+func (l LoggerWithCtx) write(lvl zapcore.Level, msg string, fields []zapcore.Field, otelFields ...zapcore.Field) {
+	ce := l.l.writer.Check(lvl, msg)
+	emit := l.l.minLevel.Enabled(lvl)
+	if ce == nil && !emit {
+		return
+	}
+
+	var caller zapcore.EntryCaller
+	if (ce != nil && ce.Caller.Defined) || (emit && l.l.caller) {
+		caller = l.l.entryCaller(3)
+	}
+
+	fields = l.logFields(l.ctx, lvl, msg, fields, otelFields, caller, time.Time{})
+	if ce == nil {
+		return
+	}
+
+	if ce.Caller.Defined && caller.Defined {
+		ce.Caller = caller
+	}
+	ce.Write(fields...)
 }
 
 func (l LoggerWithCtx) logFields(
-	ctx context.Context, lvl zapcore.Level, msg string, fields []zapcore.Field,
+	ctx context.Context, lvl zapcore.Level, msg string, fields, otelFields []zapcore.Field, caller zapcore.EntryCaller,
+	timestamp time.Time,
 ) []zapcore.Field {
-	fields = append(l.l.logFields(fields))
+	fields = l.l.redactFields(fields)
+	extraFields := l.l.redactFields(l.l.extraFields)
+	contextFields := l.l.redactFields(l.l.contextFields(ctx))
+	baggageFields := l.l.redactFields(l.l.baggageFieldsFromContext(ctx))
 
-	if lvl >= l.l.minLevel {
-		l.log(ctx, lvl, msg, convertFields(fields))
+	if l.l.minLevel.Enabled(lvl) {
+		otelFields = append(fields[:len(fields):len(fields)], l.l.redactFields(otelFields)...)
+		coreFields := l.l.redactFields(l.l.coreFields)
+		otelExtraFields := append(coreFields[:len(coreFields):len(coreFields)], extraFields...)
+		if lvl < l.l.errorDetailLevel {
+			otelFields = l.l.withoutErrorDetails(otelFields)
+			otelExtraFields = l.l.withoutErrorDetails(otelExtraFields)
+		}
+
+		var sources attributeSources
+		sources[FieldsSource] = ConvertFields(otelFields)
+		sources[ContextSource] = ConvertFields(contextFields)
+		if l.l.contextAttributes {
+			sources[ContextSource] = append(sources[ContextSource], registeredContextAttributes(ctx)...)
+		}
+		sources[BaggageSource] = ConvertFields(baggageFields)
+		sources[ExtraFieldsSource] = ConvertFields(otelExtraFields)
+
+		var spanFields []zapcore.Field
+		if trace.SpanFromContext(ctx).IsRecording() {
+			spanFields = make([]zapcore.Field, 0, len(fields)+len(contextFields)+len(baggageFields)+len(otelExtraFields))
+			spanFields = append(spanFields, fields...)
+			spanFields = append(spanFields, contextFields...)
+			spanFields = append(spanFields, baggageFields...)
+			spanFields = append(spanFields, coreFields...)
+			spanFields = append(spanFields, extraFields...)
+		}
+
+		l.log(ctx, lvl, msg, l.l.mergeAttributes(sources), spanFields, caller, timestamp)
 	}
 
-	return fields
+	fields = append(fields, contextFields...)
+	fields = append(fields, baggageFields...)
+	if l.l.traceIDFields {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			fields = append(fields,
+				zap.String("trace_id", sc.TraceID().String()),
+				zap.String("span_id", sc.SpanID().String()),
+			)
+		}
+	}
+
+	return append(fields, extraFields...)
 }
 
 func (l LoggerWithCtx) log(
-	ctx context.Context, lvl zapcore.Level, msg string, kvs []log.KeyValue,
+	ctx context.Context, lvl zapcore.Level, msg string, kvs []log.KeyValue, fields []zapcore.Field,
+	caller zapcore.EntryCaller, timestamp time.Time,
 ) {
-	if lvl >= l.l.minAnnotateLevel || lvl >= l.l.errorStatusLevel {
-		if span := trace.SpanFromContext(ctx); span.IsRecording() {
-			if lvl >= l.l.minAnnotateLevel {
-				for _, kv := range kvs {
-					span.SetAttributes(Attribute(kv.Key, kv.Value))
-				}
-			}
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		if l.l.spanHook != nil {
+			l.l.spanHook(span, lvl, msg, fields)
+		} else {
+			l.annotateSpan(span, lvl, msg, kvs, fields)
+		}
+	}
 
-			if lvl >= l.l.errorStatusLevel {
-				span.SetStatus(codes.Error, msg)
-				span.RecordError(fmt.Errorf("%s", msg))
-			}
+	if l.l.emitOnlyWithinSpan && !trace.SpanFromContext(ctx).IsRecording() {
+		return
+	}
+
+	if !l.l.emitAnnotated && lvl >= l.l.minAnnotateLevel && trace.SpanFromContext(ctx).IsRecording() {
+		return
+	}
+
+	if l.l.otelSampler != nil && !l.l.otelSampler(lvl, msg) {
+		return
+	}
+
+	if l.l.dedup != nil {
+		ok, duplicates := l.l.dedup.allow(lvl, msg)
+		if !ok {
+			return
+		}
+
+		if duplicates > 0 {
+			kvs = append(kvs, log.Int64("log.duplicates", duplicates))
 		}
 	}
 
+	// The timestamp of entries written to the core returned by NewOtelCore is
+	// the time of the zap entry, otherwise it is taken from the clock.
+	now := timestamp
+	if now.IsZero() {
+		now = l.l.clock()
+	}
+	observed := now
+	if l.l.observedClock != nil {
+		observed = l.l.observedClock()
+	}
+
 	record := log.Record{}
+	record.SetTimestamp(now)
+	record.SetObservedTimestamp(observed)
 	record.SetBody(log.StringValue(msg))
-	record.SetSeverity(convertLevel(lvl))
+	record.SetSeverity(l.l.severityMapper(lvl))
 
-	if l.l.caller {
-		if fn, file, line, ok := runtimeCaller(4 + l.l.callerDepth); ok {
-			if fn != "" {
-				kvs = append(kvs, log.String("code.function", fn))
+	if l.l.caller && caller.Defined {
+		if caller.Function != "" && l.l.callerFunction {
+			kvs = append(kvs, log.String(l.l.codeFunctionKey, caller.Function))
+		}
+		if caller.File != "" {
+			if l.l.callerFile {
+				kvs = append(kvs, log.String(l.l.codeFilepathKey, caller.File))
 			}
-			if file != "" {
-				kvs = append(kvs, log.String("code.filepath", file))
-				kvs = append(kvs, log.Int("code.lineno", line))
+			if l.l.callerLine {
+				kvs = append(kvs, log.Int(l.l.codeLinenoKey, caller.Line))
 			}
 		}
 	}
 
+	if l.l.traceCorrelation {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			kvs = append(kvs,
+				log.String("trace_id", sc.TraceID().String()),
+				log.String("span_id", sc.SpanID().String()),
+				log.String("trace_flags", sc.TraceFlags().String()),
+			)
+		}
+	}
+
+	if l.l.goroutineID {
+		if id, ok := goroutineID(); ok {
+			kvs = append(kvs, log.Int64("thread.id", id))
+		}
+	}
+
 	if l.l.stackTrace {
 		stackTrace := make([]byte, 2048)
 		n := runtime.Stack(stackTrace, false)
 		kvs = append(kvs, log.String("exception.stacktrace", string(stackTrace[:n])))
 	}
 
+	if l.l.stringifyAttributes {
+		kvs = stringifyAttributes(kvs)
+	}
+
 	if len(kvs) > 0 {
 		record.AddAttributes(kvs...)
 	}
 
-	l.l.otelLogger.Emit(ctx, record)
+	if l.l.recordCounter != nil {
+		l.l.recordCounter.add(ctx, record.Severity())
+	}
+
+	l.l.emit(ctx, lvl, record)
+}
+
+// annotateSpan sets the attributes of the record on the span, or adds them as
+// span event, and sets the status of the span depending on the level. The
+// first error field is recorded on the span, or the message if there is none.
+// It is replaced by the hook set with WithSpanHook.
+func (l LoggerWithCtx) annotateSpan(
+	span trace.Span, lvl zapcore.Level, msg string, kvs []log.KeyValue, fields []zapcore.Field,
+) {
+	if lvl >= l.l.minAnnotateLevel {
+		attrs := make([]attribute.KeyValue, 0, len(kvs))
+		for _, kv := range kvs {
+			attrs = append(attrs, truncateAttribute(Attribute(kv.Key, kv.Value), l.l.spanAttributeMaxLen))
+		}
+
+		if l.l.spanEvents {
+			span.AddEvent(msg, trace.WithAttributes(attrs...))
+		} else {
+			span.SetAttributes(attrs...)
+		}
+	}
+
+	if lvl >= l.l.errorStatusLevel {
+		span.SetStatus(codes.Error, msg)
+		err := fieldsError(fields)
+		if err == nil {
+			err = fmt.Errorf("%s", msg)
+		}
+		span.RecordError(err)
+	}
 }
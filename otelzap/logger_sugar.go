@@ -2,6 +2,7 @@ package otelzap
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"go.uber.org/zap"
@@ -19,7 +20,6 @@ import (
 // output with Infow ("info with" structured context), Info, or Infof.
 type SugaredLogger struct {
 	*zap.SugaredLogger
-	skipCaller *zap.SugaredLogger
 
 	l *Logger
 }
@@ -32,6 +32,11 @@ func (s *SugaredLogger) Desugar() *Logger {
 	return s.l
 }
 
+// Sync flushes the zap logger and the OTel records, see Logger.Sync.
+func (s *SugaredLogger) Sync() error {
+	return s.l.Sync()
+}
+
 // With adds a variadic number of fields to the logging context. It accepts a
 // mix of strongly-typed Field objects and loosely-typed key-value pairs. When
 // processing pairs, the first element of the pair is used as the field key
@@ -57,16 +62,30 @@ func (s *SugaredLogger) Desugar() *Logger {
 //	  Object("user", User{Name: "alice"}),
 //	)
 //
-// Note that the keys in key-value pairs should be strings. In development,
-// passing a non-string key panics. In production, the logger is more
-// forgiving: a separate error is logged, but the key-value pair is skipped
-// and execution continues. Passing an orphaned key triggers similar behavior:
-// panics in development and errors in production.
+// Note that the keys in key-value pairs should be strings. Malformed pairs
+// never panic, in development as in production: a key-value pair with a key
+// that is neither a string nor a zap.Field is skipped, and so is an orphaned
+// key without a value. Each is reported by a separate error log, and
+// execution continues: the pairs with invalid keys are listed in its
+// invalid_key field, with their position, key and value, and an orphaned key
+// in its ignored field.
 func (s *SugaredLogger) With(args ...interface{}) *SugaredLogger {
+	return s.withFields(s.sweetenFields(args))
+}
+
+// WithError adds an error to the logging context, like Logger.WithError.
+//
+// For example,
+//
+//	sugaredLogger.WithError(err).Errorw("failed to fetch URL", "url", url)
+func (s *SugaredLogger) WithError(err error) *SugaredLogger {
+	return s.withFields(s.l.errorFields(err))
+}
+
+func (s *SugaredLogger) withFields(fields []zapcore.Field) *SugaredLogger {
 	return &SugaredLogger{
-		SugaredLogger: s.SugaredLogger.With(args...),
-		skipCaller:    s.skipCaller,
-		l:             s.l,
+		SugaredLogger: s.SugaredLogger.Desugar().With(fields...).Sugar(),
+		l:             s.l.With(fields...),
 	}
 }
 
@@ -78,59 +97,268 @@ func (s *SugaredLogger) Ctx(ctx context.Context) SugaredLoggerWithCtx {
 	}
 }
 
+// Log logs the message at the given level. The message is built from the
+// args like fmt.Sprint, unless it is a single string.
+func (s *SugaredLogger) Log(lvl zapcore.Level, args ...interface{}) {
+	s.l.Ctx(context.Background()).write(lvl, sprint(args), nil)
+}
+
+// Debug logs the message built from the args like fmt.Sprint.
+func (s *SugaredLogger) Debug(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.DebugLevel, sprint(args), nil)
+}
+
+// Info logs the message built from the args like fmt.Sprint.
+func (s *SugaredLogger) Info(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.InfoLevel, sprint(args), nil)
+}
+
+// Warn logs the message built from the args like fmt.Sprint.
+func (s *SugaredLogger) Warn(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.WarnLevel, sprint(args), nil)
+}
+
+// Error logs the message built from the args like fmt.Sprint.
+func (s *SugaredLogger) Error(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.ErrorLevel, sprint(args), nil)
+}
+
+// DPanic logs the message built from the args like fmt.Sprint. In
+// development, the logger then panics. (See DPanicLevel for details.)
+func (s *SugaredLogger) DPanic(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.DPanicLevel, sprint(args), nil)
+}
+
+// Panic logs the message built from the args like fmt.Sprint, then panics.
+func (s *SugaredLogger) Panic(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.PanicLevel, sprint(args), nil)
+}
+
+// Fatal logs the message built from the args like fmt.Sprint, then calls
+// os.Exit.
+func (s *SugaredLogger) Fatal(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.FatalLevel, sprint(args), nil)
+}
+
+// Logf uses fmt.Sprintf to log a templated message at the given level.
+func (s *SugaredLogger) Logf(lvl zapcore.Level, template string, args ...interface{}) {
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(context.Background()).write(lvl, msg, fields, otelFields...)
+}
+
+// Debugf uses fmt.Sprintf to log a templated message.
+func (s *SugaredLogger) Debugf(template string, args ...interface{}) {
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(context.Background()).write(zap.DebugLevel, msg, fields, otelFields...)
+}
+
+// Infof uses fmt.Sprintf to log a templated message.
+func (s *SugaredLogger) Infof(template string, args ...interface{}) {
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(context.Background()).write(zap.InfoLevel, msg, fields, otelFields...)
+}
+
+// Warnf uses fmt.Sprintf to log a templated message.
+func (s *SugaredLogger) Warnf(template string, args ...interface{}) {
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(context.Background()).write(zap.WarnLevel, msg, fields, otelFields...)
+}
+
+// Errorf uses fmt.Sprintf to log a templated message.
+func (s *SugaredLogger) Errorf(template string, args ...interface{}) {
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(context.Background()).write(zap.ErrorLevel, msg, fields, otelFields...)
+}
+
+// DPanicf uses fmt.Sprintf to log a templated message. In development, the
+// logger then panics. (See DPanicLevel for details.)
+func (s *SugaredLogger) DPanicf(template string, args ...interface{}) {
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(context.Background()).write(zap.DPanicLevel, msg, fields, otelFields...)
+}
+
+// Panicf uses fmt.Sprintf to log a templated message, then panics.
+func (s *SugaredLogger) Panicf(template string, args ...interface{}) {
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(context.Background()).write(zap.PanicLevel, msg, fields, otelFields...)
+}
+
+// Fatalf uses fmt.Sprintf to log a templated message, then calls os.Exit.
+func (s *SugaredLogger) Fatalf(template string, args ...interface{}) {
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(context.Background()).write(zap.FatalLevel, msg, fields, otelFields...)
+}
+
+// Logw logs a message with some additional context at the given level. The
+// variadic key-value pairs are treated as they are in With.
+func (s *SugaredLogger) Logw(lvl zapcore.Level, msg string, keysAndValues ...interface{}) {
+	s.l.Ctx(context.Background()).write(lvl, msg, s.sweetenFields(keysAndValues))
+}
+
+// Debugw logs a message with some additional context. The variadic key-value
+// pairs are treated as they are in With.
+func (s *SugaredLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.DebugLevel, msg, s.sweetenFields(keysAndValues))
+}
+
+// Infow logs a message with some additional context. The variadic key-value
+// pairs are treated as they are in With.
+func (s *SugaredLogger) Infow(msg string, keysAndValues ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.InfoLevel, msg, s.sweetenFields(keysAndValues))
+}
+
+// Warnw logs a message with some additional context. The variadic key-value
+// pairs are treated as they are in With.
+func (s *SugaredLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.WarnLevel, msg, s.sweetenFields(keysAndValues))
+}
+
+// Errorw logs a message with some additional context. The variadic key-value
+// pairs are treated as they are in With.
+func (s *SugaredLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.ErrorLevel, msg, s.sweetenFields(keysAndValues))
+}
+
+// DPanicw logs a message with some additional context. In development, the
+// logger then panics. (See DPanicLevel for details.) The variadic key-value
+// pairs are treated as they are in With.
+func (s *SugaredLogger) DPanicw(msg string, keysAndValues ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.DPanicLevel, msg, s.sweetenFields(keysAndValues))
+}
+
+// Panicw logs a message with some additional context, then panics. The
+// variadic key-value pairs are treated as they are in With.
+func (s *SugaredLogger) Panicw(msg string, keysAndValues ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.PanicLevel, msg, s.sweetenFields(keysAndValues))
+}
+
+// Fatalw logs a message with some additional context, then calls os.Exit. The
+// variadic key-value pairs are treated as they are in With.
+func (s *SugaredLogger) Fatalw(msg string, keysAndValues ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.FatalLevel, msg, s.sweetenFields(keysAndValues))
+}
+
+// Logln logs the message built from the args like fmt.Sprintln at the given
+// level.
+func (s *SugaredLogger) Logln(lvl zapcore.Level, args ...interface{}) {
+	s.l.Ctx(context.Background()).write(lvl, sprintln(args), nil)
+}
+
+// Debugln logs the message built from the args like fmt.Sprintln.
+func (s *SugaredLogger) Debugln(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.DebugLevel, sprintln(args), nil)
+}
+
+// Infoln logs the message built from the args like fmt.Sprintln.
+func (s *SugaredLogger) Infoln(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.InfoLevel, sprintln(args), nil)
+}
+
+// Warnln logs the message built from the args like fmt.Sprintln.
+func (s *SugaredLogger) Warnln(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.WarnLevel, sprintln(args), nil)
+}
+
+// Errorln logs the message built from the args like fmt.Sprintln.
+func (s *SugaredLogger) Errorln(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.ErrorLevel, sprintln(args), nil)
+}
+
+// DPanicln logs the message built from the args like fmt.Sprintln. In
+// development, the logger then panics. (See DPanicLevel for details.)
+func (s *SugaredLogger) DPanicln(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.DPanicLevel, sprintln(args), nil)
+}
+
+// Panicln logs the message built from the args like fmt.Sprintln, then panics.
+func (s *SugaredLogger) Panicln(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.PanicLevel, sprintln(args), nil)
+}
+
+// Fatalln logs the message built from the args like fmt.Sprintln, then
+// calls os.Exit.
+func (s *SugaredLogger) Fatalln(args ...interface{}) {
+	s.l.Ctx(context.Background()).write(zap.FatalLevel, sprintln(args), nil)
+}
+
+// sprint builds the message of the Sprint-style methods like
+// zap.SugaredLogger does.
+func sprint(args []interface{}) string {
+	if len(args) == 1 {
+		if str, ok := args[0].(string); ok {
+			return str
+		}
+	}
+	return fmt.Sprint(args...)
+}
+
+// sprintln builds the message of the Sprintln-style methods like
+// zap.SugaredLogger does, without the trailing newline.
+func sprintln(args []interface{}) string {
+	msg := fmt.Sprintln(args...)
+	return msg[:len(msg)-1]
+}
+
 // Debugf uses fmt.Sprintf to log a templated message.
 func (s *SugaredLogger) DebugfContext(ctx context.Context, template string, args ...interface{}) {
-	s.logArgs(ctx, zap.DebugLevel, template, args)
-	s.Debugf(template, args...)
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(ctx).write(zap.DebugLevel, msg, fields, otelFields...)
 }
 
 // Infof uses fmt.Sprintf to log a templated message.
 func (s *SugaredLogger) InfofContext(ctx context.Context, template string, args ...interface{}) {
-	s.logArgs(ctx, zap.InfoLevel, template, args)
-	s.Infof(template, args...)
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(ctx).write(zap.InfoLevel, msg, fields, otelFields...)
 }
 
 // Warnf uses fmt.Sprintf to log a templated message.
 func (s *SugaredLogger) WarnfContext(ctx context.Context, template string, args ...interface{}) {
-	s.logArgs(ctx, zap.WarnLevel, template, args)
-	s.Warnf(template, args...)
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(ctx).write(zap.WarnLevel, msg, fields, otelFields...)
 }
 
 // Errorf uses fmt.Sprintf to log a templated message.
 func (s *SugaredLogger) ErrorfContext(ctx context.Context, template string, args ...interface{}) {
-	s.logArgs(ctx, zap.ErrorLevel, template, args)
-	s.Errorf(template, args...)
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(ctx).write(zap.ErrorLevel, msg, fields, otelFields...)
 }
 
 // DPanicf uses fmt.Sprintf to log a templated message. In development, the
 // logger then panics. (See DPanicLevel for details.)
 func (s *SugaredLogger) DPanicfContext(ctx context.Context, template string, args ...interface{}) {
-	s.logArgs(ctx, zap.DPanicLevel, template, args)
-	s.DPanicf(template, args...)
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(ctx).write(zap.DPanicLevel, msg, fields, otelFields...)
 }
 
 // Panicf uses fmt.Sprintf to log a templated message, then panics.
 func (s *SugaredLogger) PanicfContext(ctx context.Context, template string, args ...interface{}) {
-	s.logArgs(ctx, zap.PanicLevel, template, args)
-	s.Panicf(template, args...)
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(ctx).write(zap.PanicLevel, msg, fields, otelFields...)
 }
 
 // Fatalf uses fmt.Sprintf to log a templated message, then calls os.Exit.
 func (s *SugaredLogger) FatalfContext(ctx context.Context, template string, args ...interface{}) {
-	s.logArgs(ctx, zap.FatalLevel, template, args)
-	s.Fatalf(template, args...)
+	msg, fields, otelFields := s.template(template, args)
+	s.l.Ctx(ctx).write(zap.FatalLevel, msg, fields, otelFields...)
 }
 
-func (s *SugaredLogger) logArgs(
-	ctx context.Context, lvl zapcore.Level, template string, args []interface{},
-) {
-	if lvl < s.l.minLevel {
-		return
+// template formats the message like the printf-style methods of
+// zap.SugaredLogger and returns the log.template field, either as field added
+// to both zap and OTel if enabled with WithTemplateField, or as field only
+// added to the OTel record.
+func (s *SugaredLogger) template(
+	template string, args []interface{},
+) (msg string, fields, otelFields []zapcore.Field) {
+	msg = template
+	if len(args) > 0 {
+		msg = fmt.Sprintf(template, args...)
 	}
 
-	kvs := make([]zapcore.Field, 0, 1+numExtraAttr)
-	kvs = append(kvs, zap.String("log.template", template))
-	s.l.LogContext(ctx, lvl, fmt.Sprintf(template, args...), kvs...)
+	field := []zapcore.Field{zap.String("log.template", template)}
+	if s.l.templateField {
+		return msg, field, nil
+	}
+	return msg, nil, field
 }
 
 // Debugw logs a message with some additional context. The variadic key-value
@@ -138,8 +366,7 @@ func (s *SugaredLogger) logArgs(
 func (s *SugaredLogger) DebugwContext(
 	ctx context.Context, msg string, keysAndValues ...interface{},
 ) {
-	s.logKVs(ctx, zap.DebugLevel, msg, keysAndValues)
-	s.Debugw(msg, keysAndValues...)
+	s.l.Ctx(ctx).write(zap.DebugLevel, msg, s.sweetenFields(keysAndValues))
 }
 
 // Infow logs a message with some additional context. The variadic key-value
@@ -147,8 +374,7 @@ func (s *SugaredLogger) DebugwContext(
 func (s *SugaredLogger) InfowContext(
 	ctx context.Context, msg string, keysAndValues ...interface{},
 ) {
-	s.logKVs(ctx, zap.InfoLevel, msg, keysAndValues)
-	s.Infow(msg, keysAndValues...)
+	s.l.Ctx(ctx).write(zap.InfoLevel, msg, s.sweetenFields(keysAndValues))
 }
 
 // Warnw logs a message with some additional context. The variadic key-value
@@ -156,8 +382,7 @@ func (s *SugaredLogger) InfowContext(
 func (s *SugaredLogger) WarnwContext(
 	ctx context.Context, msg string, keysAndValues ...interface{},
 ) {
-	s.logKVs(ctx, zap.WarnLevel, msg, keysAndValues)
-	s.Warnw(msg, keysAndValues...)
+	s.l.Ctx(ctx).write(zap.WarnLevel, msg, s.sweetenFields(keysAndValues))
 }
 
 // Errorw logs a message with some additional context. The variadic key-value
@@ -165,8 +390,7 @@ func (s *SugaredLogger) WarnwContext(
 func (s *SugaredLogger) ErrorwContext(
 	ctx context.Context, msg string, keysAndValues ...interface{},
 ) {
-	s.logKVs(ctx, zap.ErrorLevel, msg, keysAndValues)
-	s.Errorw(msg, keysAndValues...)
+	s.l.Ctx(ctx).write(zap.ErrorLevel, msg, s.sweetenFields(keysAndValues))
 }
 
 // DPanicw logs a message with some additional context. In development, the
@@ -175,8 +399,7 @@ func (s *SugaredLogger) ErrorwContext(
 func (s *SugaredLogger) DPanicwContext(
 	ctx context.Context, msg string, keysAndValues ...interface{},
 ) {
-	s.logKVs(ctx, zap.DPanicLevel, msg, keysAndValues)
-	s.DPanicw(msg, keysAndValues...)
+	s.l.Ctx(ctx).write(zap.DPanicLevel, msg, s.sweetenFields(keysAndValues))
 }
 
 // Panicw logs a message with some additional context, then panics. The
@@ -184,8 +407,7 @@ func (s *SugaredLogger) DPanicwContext(
 func (s *SugaredLogger) PanicwContext(
 	ctx context.Context, msg string, keysAndValues ...interface{},
 ) {
-	s.logKVs(ctx, zap.PanicLevel, msg, keysAndValues)
-	s.Panicw(msg, keysAndValues...)
+	s.l.Ctx(ctx).write(zap.PanicLevel, msg, s.sweetenFields(keysAndValues))
 }
 
 // Fatalw logs a message with some additional context, then calls os.Exit. The
@@ -193,36 +415,88 @@ func (s *SugaredLogger) PanicwContext(
 func (s *SugaredLogger) FatalwContext(
 	ctx context.Context, msg string, keysAndValues ...interface{},
 ) {
-	s.logKVs(ctx, zap.FatalLevel, msg, keysAndValues)
-	s.Fatalw(msg, keysAndValues...)
+	s.l.Ctx(ctx).write(zap.FatalLevel, msg, s.sweetenFields(keysAndValues))
 }
 
-func (s *SugaredLogger) logKVs(
-	ctx context.Context, lvl zapcore.Level, msg string, args []interface{},
-) {
-	if lvl < s.l.minLevel {
-		return
-	}
+// The errors logged by sweetenFields for malformed key-value pairs, like zap's
+// SugaredLogger does.
+const (
+	oddNumberErrMsg    = "Ignored key without a value."
+	nonStringKeyErrMsg = "Ignored key-value pairs with non-string keys."
+)
 
+// sweetenFields converts the loosely-typed key-value pairs to fields. It never
+// panics, in development as in production, but skips malformed pairs and
+// reports them with an error log written to zap:
+//
+//   - a trailing key without value is logged as "Ignored key without a value."
+//     with the key in the ignored field.
+//   - pairs with keys that are neither strings nor fields are logged once as
+//     "Ignored key-value pairs with non-string keys." with the invalid_key
+//     field, an array of objects with the position, key and value of each
+//     pair.
+//
+// The reported values are passed through the redactor set with
+// WithFieldRedactor, as the ignored field and as field named like the
+// formatted key respectively.
+func (s *SugaredLogger) sweetenFields(args []interface{}) []zapcore.Field {
 	kvs := make([]zapcore.Field, 0, len(args)/2)
+	var invalid invalidPairs
 
 	for i := 0; i < len(args); i++ {
-		field := args[i]
-
-		switch field := field.(type) {
-
 		// in case it's a zapcore.Field we know that key and value are encoded in the zapcore.Field
-		case zapcore.Field:
+		if field, ok := args[i].(zapcore.Field); ok {
 			kvs = append(kvs, field)
+			continue
+		}
 
-		// in case it's a string, we assume it's key + value separate
-		case string:
-			kvs = append(kvs, zap.Any(field, args[i+1]))
+		if i == len(args)-1 {
+			s.l.Logger.Error(oddNumberErrMsg, s.l.redactFields([]zapcore.Field{zap.Any("ignored", args[i])})...)
+			break
+		}
 
-			// Also increment i because we just read args[i+1]
-			i += 1
+		// otherwise, we assume it's key + value separate
+		key, value := args[i], args[i+1]
+		if keyStr, ok := key.(string); ok {
+			kvs = append(kvs, zap.Any(keyStr, value))
+		} else {
+			invalid = append(invalid, invalidPair{position: i, key: key, value: s.l.redactValue(fmt.Sprint(key), value)})
 		}
+
+		// Also increment i because we just read args[i+1]
+		i++
+	}
+
+	if len(invalid) > 0 {
+		s.l.Logger.Error(nonStringKeyErrMsg, zap.Array("invalid_key", invalid))
 	}
 
-	s.l.LogContext(ctx, lvl, msg, kvs...)
+	return kvs
+}
+
+// invalidPair is a key-value pair with a key that is not a string. The value
+// is the redacted field, or zap.Skip if the redactor dropped it.
+type invalidPair struct {
+	position int
+	key      interface{}
+	value    zapcore.Field
+}
+
+func (p invalidPair) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt64("position", int64(p.position))
+	zap.Any("key", p.key).AddTo(enc)
+	value := p.value
+	value.Key = "value"
+	value.AddTo(enc)
+	return nil
+}
+
+type invalidPairs []invalidPair
+
+func (ps invalidPairs) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	var err error
+	for i := range ps {
+		err = errors.Join(err, enc.AppendObject(ps[i]))
+	}
+	return err
 }
@@ -22,47 +22,55 @@ func (s SugaredLoggerWithCtx) Desugar() LoggerWithCtx {
 	}
 }
 
+// WithError adds an error to the logging context, like Logger.WithError.
+func (s SugaredLoggerWithCtx) WithError(err error) SugaredLoggerWithCtx {
+	return SugaredLoggerWithCtx{
+		ctx: s.ctx,
+		s:   s.s.WithError(err),
+	}
+}
+
 // Debugf uses fmt.Sprintf to log a templated message.
 func (s SugaredLoggerWithCtx) Debugf(template string, args ...interface{}) {
-	s.s.logArgs(s.ctx, zap.DebugLevel, template, args)
-	s.s.skipCaller.Debugf(template, args...)
+	msg, fields, otelFields := s.s.template(template, args)
+	s.s.l.Ctx(s.ctx).write(zap.DebugLevel, msg, fields, otelFields...)
 }
 
 // Infof uses fmt.Sprintf to log a templated message.
 func (s SugaredLoggerWithCtx) Infof(template string, args ...interface{}) {
-	s.s.logArgs(s.ctx, zap.InfoLevel, template, args)
-	s.s.skipCaller.Infof(template, args...)
+	msg, fields, otelFields := s.s.template(template, args)
+	s.s.l.Ctx(s.ctx).write(zap.InfoLevel, msg, fields, otelFields...)
 }
 
 // Warnf uses fmt.Sprintf to log a templated message.
 func (s SugaredLoggerWithCtx) Warnf(template string, args ...interface{}) {
-	s.s.logArgs(s.ctx, zap.WarnLevel, template, args)
-	s.s.skipCaller.Warnf(template, args...)
+	msg, fields, otelFields := s.s.template(template, args)
+	s.s.l.Ctx(s.ctx).write(zap.WarnLevel, msg, fields, otelFields...)
 }
 
 // Errorf uses fmt.Sprintf to log a templated message.
 func (s SugaredLoggerWithCtx) Errorf(template string, args ...interface{}) {
-	s.s.logArgs(s.ctx, zap.ErrorLevel, template, args)
-	s.s.skipCaller.Errorf(template, args...)
+	msg, fields, otelFields := s.s.template(template, args)
+	s.s.l.Ctx(s.ctx).write(zap.ErrorLevel, msg, fields, otelFields...)
 }
 
 // DPanicf uses fmt.Sprintf to log a templated message. In development, the
 // logger then panics. (See DPanicLevel for details.)
 func (s SugaredLoggerWithCtx) DPanicf(template string, args ...interface{}) {
-	s.s.logArgs(s.ctx, zap.DPanicLevel, template, args)
-	s.s.skipCaller.DPanicf(template, args...)
+	msg, fields, otelFields := s.s.template(template, args)
+	s.s.l.Ctx(s.ctx).write(zap.DPanicLevel, msg, fields, otelFields...)
 }
 
 // Panicf uses fmt.Sprintf to log a templated message, then panics.
 func (s SugaredLoggerWithCtx) Panicf(template string, args ...interface{}) {
-	s.s.logArgs(s.ctx, zap.PanicLevel, template, args)
-	s.s.skipCaller.Panicf(template, args...)
+	msg, fields, otelFields := s.s.template(template, args)
+	s.s.l.Ctx(s.ctx).write(zap.PanicLevel, msg, fields, otelFields...)
 }
 
 // Fatalf uses fmt.Sprintf to log a templated message, then calls os.Exit.
 func (s SugaredLoggerWithCtx) Fatalf(template string, args ...interface{}) {
-	s.s.logArgs(s.ctx, zap.FatalLevel, template, args)
-	s.s.skipCaller.Fatalf(template, args...)
+	msg, fields, otelFields := s.s.template(template, args)
+	s.s.l.Ctx(s.ctx).write(zap.FatalLevel, msg, fields, otelFields...)
 }
 
 // Debugw logs a message with some additional context. The variadic key-value
@@ -72,49 +80,42 @@ func (s SugaredLoggerWithCtx) Fatalf(template string, args ...interface{}) {
 //
 //	s.With(keysAndValues).Debug(msg)
 func (s SugaredLoggerWithCtx) Debugw(msg string, keysAndValues ...interface{}) {
-	s.s.logKVs(s.ctx, zap.DebugLevel, msg, keysAndValues)
-	s.s.skipCaller.Debugw(msg, keysAndValues...)
+	s.s.l.Ctx(s.ctx).write(zap.DebugLevel, msg, s.s.sweetenFields(keysAndValues))
 }
 
 // Infow logs a message with some additional context. The variadic key-value
 // pairs are treated as they are in With.
 func (s SugaredLoggerWithCtx) Infow(msg string, keysAndValues ...interface{}) {
-	s.s.logKVs(s.ctx, zap.InfoLevel, msg, keysAndValues)
-	s.s.skipCaller.Infow(msg, keysAndValues...)
+	s.s.l.Ctx(s.ctx).write(zap.InfoLevel, msg, s.s.sweetenFields(keysAndValues))
 }
 
 // Warnw logs a message with some additional context. The variadic key-value
 // pairs are treated as they are in With.
 func (s SugaredLoggerWithCtx) Warnw(msg string, keysAndValues ...interface{}) {
-	s.s.logKVs(s.ctx, zap.WarnLevel, msg, keysAndValues)
-	s.s.skipCaller.Warnw(msg, keysAndValues...)
+	s.s.l.Ctx(s.ctx).write(zap.WarnLevel, msg, s.s.sweetenFields(keysAndValues))
 }
 
 // Errorw logs a message with some additional context. The variadic key-value
 // pairs are treated as they are in With.
 func (s SugaredLoggerWithCtx) Errorw(msg string, keysAndValues ...interface{}) {
-	s.s.logKVs(s.ctx, zap.ErrorLevel, msg, keysAndValues)
-	s.s.skipCaller.Errorw(msg, keysAndValues...)
+	s.s.l.Ctx(s.ctx).write(zap.ErrorLevel, msg, s.s.sweetenFields(keysAndValues))
 }
 
 // DPanicw logs a message with some additional context. In development, the
 // logger then panics. (See DPanicLevel for details.) The variadic key-value
 // pairs are treated as they are in With.
 func (s SugaredLoggerWithCtx) DPanicw(msg string, keysAndValues ...interface{}) {
-	s.s.logKVs(s.ctx, zap.DPanicLevel, msg, keysAndValues)
-	s.s.skipCaller.DPanicw(msg, keysAndValues...)
+	s.s.l.Ctx(s.ctx).write(zap.DPanicLevel, msg, s.s.sweetenFields(keysAndValues))
 }
 
 // Panicw logs a message with some additional context, then panics. The
 // variadic key-value pairs are treated as they are in With.
 func (s SugaredLoggerWithCtx) Panicw(msg string, keysAndValues ...interface{}) {
-	s.s.logKVs(s.ctx, zap.PanicLevel, msg, keysAndValues)
-	s.s.skipCaller.Panicw(msg, keysAndValues...)
+	s.s.l.Ctx(s.ctx).write(zap.PanicLevel, msg, s.s.sweetenFields(keysAndValues))
 }
 
 // Fatalw logs a message with some additional context, then calls os.Exit. The
 // variadic key-value pairs are treated as they are in With.
 func (s SugaredLoggerWithCtx) Fatalw(msg string, keysAndValues ...interface{}) {
-	s.s.logKVs(s.ctx, zap.FatalLevel, msg, keysAndValues)
-	s.s.skipCaller.Fatalw(msg, keysAndValues...)
+	s.s.l.Ctx(s.ctx).write(zap.FatalLevel, msg, s.s.sweetenFields(keysAndValues))
 }
@@ -3,13 +3,40 @@ package otelzap_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/aws/smithy-go/logging"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/aws/smithy-go/waiter"
+	pkgerrors "github.com/pkg/errors"
 	"github.com/sierrasoftworks/humane-errors-go"
 	"github.com/spechtlabs/go-otel-utils/otelzap"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/embedded"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/log/logtest"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func initLogger() *bytes.Buffer {
@@ -73,3 +100,1954 @@ func TestErrorCtxSugared(t *testing.T) {
 	otelzap.L().Ctx(ctx).Sugar().Errorw("Test Message", "foo", "bar")
 	assert.Contains(t, buf.String(), "error\tTest Message\t{\"foo\": \"bar\"}")
 }
+
+func TestSugaredOddKeysAndValues(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core), otelzap.WithLoggerProvider(recorder))
+
+	require.NotPanics(t, func() {
+		logger.Sugar().InfowContext(context.Background(), "Info Message", "foo", "bar", "dangling")
+	})
+
+	entries := observed.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+	assert.Equal(t, map[string]interface{}{"ignored": "dangling"}, entries[0].ContextMap())
+	assert.Equal(t, "Info Message", entries[1].Message)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, entries[1].ContextMap())
+
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, "bar", attrs["foo"].AsString())
+	assert.NotContains(t, attrs, "dangling")
+}
+
+func TestSugaredMalformedPairsRedacted(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithFieldRedactor(func(field zapcore.Field) zapcore.Field {
+			switch field.Key {
+			case "ignored":
+				return zap.String(field.Key, "***")
+			case "42":
+				return zap.Skip()
+			}
+			return field
+		}),
+	)
+
+	logger.Sugar().Infow("Info Message", 42, "secret", "dangling")
+
+	entries := observed.AllUntimed()
+	require.Len(t, entries, 3)
+	assert.Equal(t, map[string]interface{}{"ignored": "***"}, entries[0].ContextMap())
+	assert.Equal(t, map[string]interface{}{
+		"invalid_key": []interface{}{
+			map[string]interface{}{"position": int64(0), "key": int64(42)},
+		},
+	}, entries[1].ContextMap())
+}
+
+func TestSugaredMixedKeysAndValues(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core), otelzap.WithLoggerProvider(recorder))
+
+	logger.Sugar().InfowContext(context.Background(), "Info Message",
+		zap.String("field", "value"),
+		"foo", "bar",
+		42, "invalid",
+		zap.Int("count", 3),
+		"baz", true,
+	)
+
+	entries := observed.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+	assert.Equal(t, map[string]interface{}{
+		"invalid_key": []interface{}{
+			map[string]interface{}{"position": int64(3), "key": int64(42), "value": "invalid"},
+		},
+	}, entries[0].ContextMap())
+	assert.Equal(t, map[string]interface{}{
+		"field": "value",
+		"foo":   "bar",
+		"count": int64(3),
+		"baz":   true,
+	}, entries[1].ContextMap())
+
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Len(t, attrs, 4+3) // the fields and the caller attributes
+	assert.Equal(t, "value", attrs["field"].AsString())
+	assert.Equal(t, "bar", attrs["foo"].AsString())
+	assert.Equal(t, int64(3), attrs["count"].AsInt64())
+	assert.True(t, attrs["baz"].AsBool())
+}
+
+func TestDevConsoleEncoderConfig(t *testing.T) {
+	buf := &bytes.Buffer{}
+	core := zapcore.NewCore(
+		zapcore.NewConsoleEncoder(otelzap.NewDevConsoleEncoderConfig()),
+		zapcore.AddSync(buf),
+		zap.NewAtomicLevelAt(zapcore.DebugLevel),
+	)
+
+	otelzap.New(zap.New(core)).Warn("Test Message", zap.String("foo", "bar"))
+	assert.Contains(t, buf.String(), "Z\tWARN\tTest Message\t{\"foo\": \"bar\"}")
+}
+
+type blockingProvider struct {
+	embedded.LoggerProvider
+	release chan struct{}
+	pending *atomic.Int64
+}
+
+func (p blockingProvider) Logger(string, ...log.LoggerOption) log.Logger {
+	return blockingLogger{release: p.release, pending: p.pending}
+}
+
+type blockingLogger struct {
+	embedded.Logger
+	release chan struct{}
+	pending *atomic.Int64
+}
+
+func (l blockingLogger) Emit(context.Context, log.Record) {
+	if l.pending != nil {
+		l.pending.Add(1)
+		defer l.pending.Add(-1)
+	}
+	<-l.release
+}
+
+func (l blockingLogger) Enabled(context.Context, log.EnabledParameters) bool {
+	return true
+}
+
+func TestEmitTimeout(t *testing.T) {
+	provider := blockingProvider{release: make(chan struct{})}
+	defer close(provider.release)
+
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(provider),
+		otelzap.WithEmitTimeout(10*time.Millisecond),
+	)
+
+	logger.Ctx(context.Background()).Info("Test Message")
+	assert.Equal(t, uint64(1), logger.DroppedEmits())
+}
+
+func TestEmitTimeoutBoundsPendingEmits(t *testing.T) {
+	provider := blockingProvider{release: make(chan struct{}), pending: &atomic.Int64{}}
+
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(provider),
+		otelzap.WithEmitTimeout(time.Millisecond),
+	)
+
+	for i := 0; i < 100; i++ {
+		logger.Ctx(context.Background()).Info("Test Message")
+	}
+	assert.Equal(t, uint64(100), logger.DroppedEmits())
+	assert.Eventually(t, func() bool { return provider.pending.Load() == 64 }, time.Second, time.Millisecond)
+
+	close(provider.release)
+	assert.Eventually(t, func() bool { return provider.pending.Load() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestEmitCanceledContext(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(recorder))
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	logger.Ctx(ctx).Error("Test Message")
+
+	// With an emit timeout, the record isn't dropped right away either.
+	timeoutLogger := logger.Clone(otelzap.WithEmitTimeout(time.Second))
+	timeoutLogger.Ctx(ctx).Error("Test Message")
+	assert.Equal(t, uint64(0), timeoutLogger.DroppedEmits())
+
+	var records []logtest.EmittedRecord
+	for _, scope := range recorder.Result() {
+		records = append(records, scope.Records...)
+	}
+	require.Len(t, records, 2)
+
+	record := records[0]
+	assert.Equal(t, "Test Message", record.Body().AsString())
+	assert.NoError(t, record.Context().Err())
+	assert.Equal(t, span.SpanContext(), trace.SpanContextFromContext(record.Context()))
+}
+
+type flushingProvider struct {
+	*logtest.Recorder
+	flushed int
+}
+
+func (p *flushingProvider) ForceFlush(context.Context) error {
+	p.flushed++
+	return nil
+}
+
+func TestSync(t *testing.T) {
+	provider := &flushingProvider{Recorder: logtest.NewRecorder()}
+	logger := otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(provider))
+
+	require.NoError(t, logger.Sync())
+	assert.Equal(t, 1, provider.flushed)
+}
+
+func TestStructuredFields(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(recorder))
+
+	user := zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("name", "jane")
+		enc.AddInt("age", 42)
+		enc.OpenNamespace("address")
+		enc.AddString("city", "Berlin")
+		return nil
+	})
+	type point struct {
+		X int     `json:"x"`
+		Y float64 `json:"y"`
+	}
+
+	logger.Ctx(context.Background()).Info("Info Message",
+		zap.Object("user", user),
+		zap.Objects("users", []zapcore.ObjectMarshaler{user}),
+		zap.Ints("ids", []int{1, 2}),
+		zap.Any("point", point{X: 1, Y: 2.5}),
+	)
+
+	attrs := recordAttributes(lastRecord(t, recorder))
+
+	wantUser := log.MapValue(
+		log.String("name", "jane"),
+		log.Int("age", 42),
+		log.Map("address", log.String("city", "Berlin")),
+	)
+	assert.True(t, wantUser.Equal(attrs["user"]), attrs["user"].String())
+	assert.True(t, log.SliceValue(wantUser).Equal(attrs["users"]), attrs["users"].String())
+	assert.True(t, log.SliceValue(log.IntValue(1), log.IntValue(2)).Equal(attrs["ids"]), attrs["ids"].String())
+
+	require.Equal(t, log.KindMap, attrs["point"].Kind())
+	coords := map[string]log.Value{}
+	for _, kv := range attrs["point"].AsMap() {
+		coords[kv.Key] = kv.Value
+	}
+	assert.Equal(t, int64(1), coords["x"].AsInt64())
+	assert.Equal(t, 2.5, coords["y"].AsFloat64())
+}
+
+func TestWithFields(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithFields(zap.String("component", "billing")),
+	)
+
+	logger.Ctx(context.Background()).Info("Info Message", zap.String("foo", "bar"))
+
+	require.Equal(t, 1, observed.Len())
+	assert.Equal(t, map[string]interface{}{"component": "billing", "foo": "bar"}, observed.All()[0].ContextMap())
+
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, "billing", attrs["component"].AsString())
+	assert.Equal(t, "bar", attrs["foo"].AsString())
+
+	// Cloning with more fields must not affect the original logger.
+	clone := logger.Clone(otelzap.WithFields(zap.String("version", "1.0.0")))
+	logger.Clone(otelzap.WithFields(zap.String("version", "2.0.0")))
+	clone.Ctx(context.Background()).Info("Info Message")
+	assert.Equal(t, map[string]interface{}{"component": "billing", "version": "1.0.0"}, observed.All()[1].ContextMap())
+}
+
+func TestEmitWithoutContext(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithMinLevel(zap.DebugLevel),
+		otelzap.WithFields(zap.String("component", "billing")),
+	)
+
+	logger.Debug("Debug Message", zap.String("foo", "bar"))
+	logger.Info("Info Message")
+	logger.Warn("Warn Message")
+	logger.Error("Error Message")
+	logger.Log(zap.InfoLevel, "Log Message")
+	logger.Logf(logging.Warn, "Logf %s", "Message")
+
+	assert.Equal(t, 6, observed.Len())
+
+	var records []logtest.EmittedRecord
+	for _, scope := range recorder.Result() {
+		records = append(records, scope.Records...)
+	}
+	require.Len(t, records, 6)
+
+	assert.Equal(t, "Debug Message", records[0].Body().AsString())
+	attrs := recordAttributes(records[0])
+	assert.Equal(t, "bar", attrs["foo"].AsString())
+	assert.Equal(t, "billing", attrs["component"].AsString())
+	assert.Contains(t, attrs["code.function"].AsString(), "TestEmitWithoutContext")
+
+	assert.Equal(t, log.SeverityError, records[3].Severity())
+	assert.Equal(t, "Logf Message", records[5].Body().AsString())
+	assert.Equal(t, log.SeverityWarn, records[5].Severity())
+}
+
+func TestSugaredEmitWithoutContext(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core), otelzap.WithLoggerProvider(recorder)).Sugar().With("component", "billing")
+
+	logger.Info("Info ", "Message")
+	logger.Warnf("Warnf %s", "Message")
+	logger.Errorw("Errorw Message", "foo", "bar")
+	logger.Infoln("Infoln", "Message")
+	logger.Logw(zap.WarnLevel, "Logw Message")
+
+	require.Equal(t, 5, observed.Len())
+	for _, entry := range observed.All() {
+		assert.Equal(t, "billing", entry.ContextMap()["component"])
+	}
+
+	var records []logtest.EmittedRecord
+	for _, scope := range recorder.Result() {
+		records = append(records, scope.Records...)
+	}
+	require.Len(t, records, 5)
+
+	var bodies []string
+	for _, record := range records {
+		bodies = append(bodies, record.Body().AsString())
+		assert.Equal(t, "billing", recordAttributes(record)["component"].AsString())
+	}
+	assert.Equal(t, []string{"Info Message", "Warnf Message", "Errorw Message", "Infoln Message", "Logw Message"}, bodies)
+
+	attrs := recordAttributes(records[1])
+	assert.Equal(t, "Warnf %s", attrs["log.template"].AsString())
+	assert.Contains(t, attrs["code.function"].AsString(), "TestSugaredEmitWithoutContext")
+	assert.Equal(t, "bar", recordAttributes(records[2])["foo"].AsString())
+}
+
+func TestZapLevel(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithMinLevel(zap.InfoLevel),
+		otelzap.WithZapLevel(zap.WarnLevel),
+	)
+
+	bodies := func() []string {
+		var bodies []string
+		for _, scope := range recorder.Result() {
+			for _, record := range scope.Records {
+				bodies = append(bodies, record.Body().AsString())
+			}
+		}
+		return bodies
+	}
+
+	ctx := context.Background()
+	logger.Ctx(ctx).Debug("Debug Message")
+	logger.Ctx(ctx).Info("Info Message")
+	logger.Ctx(ctx).Warn("Warn Message")
+
+	// The zap level gates the zap output only, the min level the OTel records.
+	require.Equal(t, 1, observed.Len())
+	assert.Equal(t, "Warn Message", observed.All()[0].Message)
+	assert.Equal(t, []string{"Info Message", "Warn Message"}, bodies())
+	assert.Equal(t, zap.WarnLevel, logger.ZapLevel())
+	assert.True(t, logger.Enabled(zap.InfoLevel))
+	assert.False(t, logger.Enabled(zap.DebugLevel))
+
+	logger.SetZapLevel(zap.DebugLevel)
+	logger.SetMinLevel(zap.ErrorLevel)
+	logger.With(zap.String("foo", "bar")).Ctx(ctx).Debug("Debug Message")
+	logger.Sugar().Info("Info Message")
+
+	require.Equal(t, 3, observed.Len())
+	assert.Equal(t, "Debug Message", observed.All()[1].Message)
+	assert.Equal(t, "Info Message", observed.All()[2].Message)
+	assert.Equal(t, []string{"Info Message", "Warn Message"}, bodies())
+}
+
+func TestContextFields(t *testing.T) {
+	type tenantKey struct{}
+
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithContextFields(func(ctx context.Context) []zapcore.Field {
+			if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+				return []zapcore.Field{zap.String("tenant.id", tenant)}
+			}
+			return nil
+		}),
+	)
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	logger.Ctx(ctx).Info("Info Message", zap.String("foo", "bar"))
+	logger.Sugar().InfowContext(ctx, "Infow Message")
+	logger.Info("Info Message without context")
+
+	require.Equal(t, 3, observed.Len())
+	assert.Equal(t, map[string]interface{}{"foo": "bar", "tenant.id": "acme"}, observed.All()[0].ContextMap())
+	assert.Equal(t, map[string]interface{}{"tenant.id": "acme"}, observed.All()[1].ContextMap())
+	assert.Empty(t, observed.All()[2].ContextMap())
+
+	var records []logtest.EmittedRecord
+	for _, scope := range recorder.Result() {
+		records = append(records, scope.Records...)
+	}
+	require.Len(t, records, 3)
+	assert.Equal(t, "acme", recordAttributes(records[0])["tenant.id"].AsString())
+	assert.Equal(t, "acme", recordAttributes(records[1])["tenant.id"].AsString())
+	assert.NotContains(t, recordAttributes(records[2]), "tenant.id")
+}
+
+func TestLogCtx(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core, zap.AddCaller()), otelzap.WithLoggerProvider(recorder))
+
+	logger.Ctx(context.Background()).Log(zap.WarnLevel, "Warn Message", zap.String("foo", "bar"))
+	logger.Ctx(context.Background()).Log(zap.DebugLevel, "Debug Message")
+
+	require.Equal(t, 2, observed.Len())
+	assert.Equal(t, zap.WarnLevel, observed.All()[0].Level)
+	assert.Contains(t, observed.All()[0].Caller.File, "logger_test.go")
+
+	record := lastRecord(t, recorder)
+	assert.Equal(t, "Warn Message", record.Body().AsString())
+	assert.Equal(t, log.SeverityWarn, record.Severity())
+	attrs := recordAttributes(record)
+	assert.Equal(t, "bar", attrs["foo"].AsString())
+	assert.Contains(t, attrs["code.function"].AsString(), "TestLogCtx")
+}
+
+func TestOtelCore(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	core, observed := observer.New(zapcore.DebugLevel)
+	logger := zap.New(zapcore.NewTee(core, otelzap.NewOtelCore(recorder)), zap.AddCaller())
+
+	logger.Debug("Debug Message")
+	logger.With(zap.String("foo", "bar")).Warn("Warn Message", zap.Int("count", 3))
+
+	assert.Equal(t, 2, observed.Len())
+
+	var records []logtest.EmittedRecord
+	for _, scope := range recorder.Result() {
+		records = append(records, scope.Records...)
+	}
+	require.Len(t, records, 1)
+
+	record := records[0]
+	assert.Equal(t, "Warn Message", record.Body().AsString())
+	assert.Equal(t, log.SeverityWarn, record.Severity())
+
+	attrs := recordAttributes(record)
+	assert.Equal(t, "bar", attrs["foo"].AsString())
+	assert.Equal(t, int64(3), attrs["count"].AsInt64())
+	assert.Contains(t, attrs["code.function"].AsString(), "TestOtelCore")
+}
+
+func TestOtelCoreEntry(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	core, observed := observer.New(zapcore.DebugLevel)
+	clock := func() time.Time { return time.Unix(0, 0) }
+	logger := zap.New(zapcore.NewTee(core, otelzap.NewOtelCore(recorder, otelzap.WithClock(clock))), zap.AddCaller())
+
+	logger.With(zap.String("foo", "bar")).Named("db").Info("Info Message")
+	_, _, line, _ := runtime.Caller(0)
+
+	require.Equal(t, 1, observed.Len())
+	entry := observed.All()[0]
+
+	scopes := recorder.Result()
+	require.Len(t, scopes, 2)
+	assert.Equal(t, "db", scopes[1].Name)
+	require.Len(t, scopes[1].Records, 1)
+
+	record := scopes[1].Records[0]
+	assert.True(t, entry.Time.Equal(record.Timestamp()))
+
+	attrs := recordAttributes(record)
+	assert.Equal(t, "bar", attrs["foo"].AsString())
+	assert.Contains(t, attrs["code.function"].AsString(), "TestOtelCoreEntry")
+	assert.Equal(t, int64(line-1), attrs["code.lineno"].AsInt64())
+	assert.Equal(t, entry.Caller.Line, line-1)
+}
+
+func lastRecord(t *testing.T, recorder *logtest.Recorder) logtest.EmittedRecord {
+	t.Helper()
+
+	var records []logtest.EmittedRecord
+	for _, scope := range recorder.Result() {
+		records = append(records, scope.Records...)
+	}
+
+	require.NotEmpty(t, records)
+	return records[len(records)-1]
+}
+
+func recordAttributes(record logtest.EmittedRecord) map[string]log.Value {
+	attrs := map[string]log.Value{}
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	return attrs
+}
+
+func TestErrorDetailLevel(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithErrorDetailLevel(zap.ErrorLevel),
+	)
+	ctx := context.Background()
+	humaneErr := humane.New("message", "advice")
+
+	hasAdvice := func(attrs map[string]log.Value) bool {
+		for key := range attrs {
+			if strings.HasPrefix(key, "error_advice") {
+				return true
+			}
+		}
+		return false
+	}
+
+	logger.WithError(humaneErr).Ctx(ctx).Warn("Test Message")
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Contains(t, attrs, "exception.message")
+	assert.False(t, hasAdvice(attrs))
+
+	logger.WithError(humaneErr).Ctx(ctx).Error("Test Message")
+	attrs = recordAttributes(lastRecord(t, recorder))
+	assert.Contains(t, attrs, "exception.message")
+	assert.True(t, hasAdvice(attrs))
+}
+
+func TestWithErrorChain(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	logger := otelzap.New(zap.New(core), otelzap.WithLoggerProvider(logtest.NewRecorder()))
+
+	err := fmt.Errorf("outer: %w", fmt.Errorf("middle: %w", pkgerrors.New("inner")))
+	logger.WithError(err).Ctx(context.Background()).Error("Test Message")
+
+	require.Equal(t, 1, observed.Len())
+	fields := observed.All()[0].ContextMap()
+	assert.Equal(t, "outer: middle: inner", fields["error"])
+	assert.Equal(t, []interface{}{"middle: inner", "inner"}, fields["error_chain"])
+	assert.Contains(t, fields["error_stack"], "TestWithErrorChain")
+	assert.NotContains(t, fields, "error_advice")
+}
+
+func TestSugaredWithError(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core), otelzap.WithLoggerProvider(recorder)).Sugar()
+
+	err := humane.Wrap(errors.New("cause"), "message", "advice")
+	logger.WithError(err).Errorw("Test Message", "foo", "bar")
+	logger.Ctx(context.Background()).WithError(err).Errorf("Test %s", "Message")
+
+	require.Equal(t, 2, observed.Len())
+	for _, entry := range observed.All() {
+		fields := entry.ContextMap()
+		assert.Equal(t, "message", fields["error"])
+		assert.Equal(t, []interface{}{"advice"}, fields["error_advice"])
+		assert.Equal(t, []interface{}{"cause"}, fields["error_chain"])
+	}
+	assert.Equal(t, "bar", observed.All()[0].ContextMap()["foo"])
+
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, "message", attrs["exception.message"].AsString())
+	assert.Contains(t, attrs, "error_advice")
+}
+
+func TestErrorKeys(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithErrorKeys("err", "err.advice", ""),
+		otelzap.WithErrorDetailLevel(zap.ErrorLevel),
+	)
+
+	logger.WithError(humane.New("message", "advice")).Ctx(context.Background()).Warn("Test Message")
+
+	require.Equal(t, 1, observed.Len())
+	assert.Equal(t, map[string]interface{}{
+		"err":        "message",
+		"err.advice": []interface{}{"advice"},
+	}, observed.All()[0].ContextMap())
+
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, "message", attrs["exception.message"].AsString())
+	for key := range attrs {
+		assert.False(t, strings.HasPrefix(key, "err.advice"), key)
+	}
+}
+
+func TestDedupCacheShared(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	cache := otelzap.NewDedupCache(200*time.Millisecond, 0)
+	loggers := []*otelzap.Logger{
+		otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(recorder), otelzap.WithDedupCache(cache)),
+		otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(recorder), otelzap.WithDedupCache(cache)),
+	}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(logger *otelzap.Logger) {
+			defer wg.Done()
+			logger.Ctx(ctx).Error("Test Message")
+		}(loggers[i%len(loggers)])
+	}
+	wg.Wait()
+
+	records := func() []logtest.EmittedRecord {
+		var records []logtest.EmittedRecord
+		for _, scope := range recorder.Result() {
+			records = append(records, scope.Records...)
+		}
+		return records
+	}
+	assert.Len(t, records(), 1)
+
+	time.Sleep(250 * time.Millisecond)
+	recorder.Reset()
+	loggers[0].Ctx(ctx).Error("Test Message")
+	require.Len(t, records(), 1)
+	assert.Equal(t, int64(49), recordAttributes(records()[0])["log.duplicates"].AsInt64())
+}
+
+type recordOnlySampler struct{}
+
+func (recordOnlySampler) ShouldSample(sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return sdktrace.SamplingResult{Decision: sdktrace.RecordOnly}
+}
+
+func (recordOnlySampler) Description() string {
+	return "RecordOnly"
+}
+
+func TestDeferLogExportUntilSpanEnd(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithDeferLogExportUntilSpanEnd(),
+	)
+
+	for _, tt := range []struct {
+		name    string
+		sampler sdktrace.Sampler
+		want    int
+	}{
+		{name: "sampled", sampler: sdktrace.AlwaysSample(), want: 2},
+		{name: "not sampled", sampler: recordOnlySampler{}, want: 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder.Reset()
+			tp := sdktrace.NewTracerProvider(
+				sdktrace.WithSampler(tt.sampler),
+				sdktrace.WithSpanProcessor(logger.SpanProcessor()),
+			)
+
+			ctx, span := tp.Tracer("test").Start(context.Background(), "span")
+			logger.Ctx(ctx).Info("first")
+			logger.Ctx(ctx).Info("second")
+			assert.Empty(t, recorder.Result()[0].Records)
+
+			span.End()
+			records := recorder.Result()[0].Records
+			require.Len(t, records, tt.want)
+			if tt.want > 0 {
+				assert.Equal(t, "first", records[0].Body().AsString())
+				assert.Equal(t, "second", records[1].Body().AsString())
+			}
+		})
+	}
+}
+
+func TestDeferLogExportEvictsExpiredSpans(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithDeferLogExportUntilSpanEnd(),
+		otelzap.WithClock(func() time.Time { return now }),
+	)
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(recordOnlySampler{}),
+		sdktrace.WithSpanProcessor(logger.SpanProcessor()),
+	)
+
+	ctx, leaked := tp.Tracer("test").Start(context.Background(), "leaked")
+	defer leaked.End()
+	logger.Ctx(ctx).Info("leaked")
+
+	now = now.Add(5 * time.Minute)
+	ctx, span := tp.Tracer("test").Start(context.Background(), "span")
+	logger.Ctx(ctx).Info("held back")
+
+	records := recorder.Result()[0].Records
+	require.Len(t, records, 1)
+	assert.Equal(t, "leaked", records[0].Body().AsString())
+
+	// The records of spans that end in time still follow their sampling.
+	span.End()
+	assert.Len(t, recorder.Result()[0].Records, 1)
+}
+
+func TestDeferLogExportEvictsWhenFull(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithDeferLogExportUntilSpanEnd(),
+		otelzap.WithClock(func() time.Time { return now }),
+	)
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(recordOnlySampler{}),
+		sdktrace.WithSpanProcessor(logger.SpanProcessor()),
+	)
+
+	for i := 0; i < 10; i++ {
+		ctx, span := tp.Tracer("test").Start(context.Background(), "span")
+		defer span.End()
+		for j := 0; j < 1000; j++ {
+			logger.Ctx(ctx).Info(fmt.Sprintf("span %d", i))
+		}
+		now = now.Add(time.Millisecond)
+	}
+	assert.Empty(t, recorder.Result()[0].Records)
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "span")
+	defer span.End()
+	logger.Ctx(ctx).Info("span 10")
+
+	records := recorder.Result()[0].Records
+	require.Len(t, records, 1000)
+	assert.Equal(t, "span 0", records[0].Body().AsString())
+}
+
+func TestStringifyAttributes(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithStringifyAttributes(),
+	)
+
+	logger.Ctx(context.Background()).Info("Test Message", zap.Int("count", 42), zap.Bool("ok", true))
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, log.StringValue("42"), attrs["count"])
+	assert.Equal(t, log.StringValue("true"), attrs["ok"])
+	assert.Equal(t, log.KindString, attrs["code.lineno"].Kind())
+}
+
+func TestCount(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	reader := sdkmetric.NewManualReader()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+
+	ctx := context.Background()
+	logger.Ctx(ctx).Count("request served", "requests", zap.String("route", "/health"))
+	logger.Ctx(ctx).Count("request served", "requests", zap.String("route", "/health"))
+
+	record := lastRecord(t, recorder)
+	assert.Equal(t, "request served", record.Body().AsString())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+
+	m := rm.ScopeMetrics[0].Metrics[0]
+	assert.Equal(t, "requests", m.Name)
+
+	sum, ok := m.Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, int64(2), sum.DataPoints[0].Value)
+
+	route, ok := sum.DataPoints[0].Attributes.Value("route")
+	require.True(t, ok)
+	assert.Equal(t, "/health", route.AsString())
+}
+
+func TestCountAttributes(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(logtest.NewRecorder()),
+		otelzap.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+
+	ctx := context.Background()
+	logger.Ctx(ctx).Count("request failed", "failures",
+		zap.String("route", "/health"),
+		zap.Int("status", 503),
+		zap.Bool("retried", true),
+		zap.Float64("ratio", 0.5),
+		zap.Error(errors.New("connection refused")),
+	)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	sum, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, sum.DataPoints, 1)
+
+	attrs := sum.DataPoints[0].Attributes
+	assert.Equal(t, 4, attrs.Len())
+
+	route, _ := attrs.Value("route")
+	assert.Equal(t, attribute.StringValue("/health"), route)
+	status, _ := attrs.Value("status")
+	assert.Equal(t, attribute.Int64Value(503), status)
+	retried, _ := attrs.Value("retried")
+	assert.Equal(t, attribute.BoolValue(true), retried)
+	ratio, _ := attrs.Value("ratio")
+	assert.Equal(t, attribute.Float64Value(0.5), ratio)
+}
+
+func TestUnhandledFieldType(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	undo := otelzap.ReplaceGlobals(otelzap.New(zap.New(core)))
+	defer undo()
+
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(recorder))
+
+	exotic := zapcore.Field{Key: "exotic", Type: zapcore.FieldType(200), Interface: struct{ ID int }{ID: 7}}
+	logger.Ctx(context.Background()).Info("Test Message", exotic)
+	logger.Ctx(context.Background()).Info("Test Message", exotic)
+
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, log.StringValue("{7}"), attrs["exotic"])
+	assert.Equal(t, 1, observed.FilterMessageSnippet("unhandled field type").Len())
+}
+
+func TestGoroutineIDAttribute(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithGoroutineIDAttribute(),
+	)
+
+	logger.Ctx(context.Background()).Info("Test Message")
+	first := recordAttributes(lastRecord(t, recorder))["thread.id"]
+	require.Equal(t, log.KindInt64, first.Kind())
+	assert.Positive(t, first.AsInt64())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Ctx(context.Background()).Info("Test Message")
+	}()
+	<-done
+
+	second := recordAttributes(lastRecord(t, recorder))["thread.id"]
+	assert.NotEqual(t, first.AsInt64(), second.AsInt64())
+}
+
+func TestAttributePrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []otelzap.Option
+		with   bool
+		expect string
+	}{
+		{name: "fields over extra fields", expect: "field"},
+		{name: "fields over With", with: true, expect: "field"},
+		{
+			name:   "extra fields first",
+			opts:   []otelzap.Option{otelzap.WithAttributePrecedence(otelzap.ExtraFieldsSource)},
+			expect: "extra",
+		},
+		{
+			name:   "With first",
+			opts:   []otelzap.Option{otelzap.WithAttributePrecedence(otelzap.ExtraFieldsSource, otelzap.FieldsSource)},
+			with:   true,
+			expect: "with",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := logtest.NewRecorder()
+			opts := append([]otelzap.Option{
+				otelzap.WithLoggerProvider(recorder),
+				otelzap.WithExtraFields(zap.String("key", "extra")),
+			}, tt.opts...)
+			logger := otelzap.New(zap.NewNop(), opts...)
+			if tt.with {
+				logger = otelzap.New(zap.NewNop(), append(opts[:1:1], tt.opts...)...).With(zap.String("key", "with"))
+			}
+
+			logger.Ctx(context.Background()).Info("Test Message", zap.String("key", "field"), zap.Int("other", 1))
+
+			var values []string
+			record := lastRecord(t, recorder)
+			record.WalkAttributes(func(kv log.KeyValue) bool {
+				if kv.Key == "key" {
+					values = append(values, kv.Value.AsString())
+				}
+				return true
+			})
+			assert.Equal(t, []string{tt.expect}, values)
+			assert.Equal(t, int64(1), recordAttributes(record)["other"].AsInt64())
+		})
+	}
+}
+
+func TestGlobalLoggerProviderSetLater(t *testing.T) {
+	logger := otelzap.New(zap.NewNop())
+
+	recorder := logtest.NewRecorder()
+	global.SetLoggerProvider(recorder)
+
+	logger.Ctx(context.Background()).Info("Test Message")
+	record := lastRecord(t, recorder)
+	assert.Equal(t, "Test Message", record.Body().AsString())
+}
+
+type tenantKey struct{}
+
+type requestIDKey struct{}
+
+func TestRegisteredContextAttributes(t *testing.T) {
+	otelzap.RegisterContextAttribute(tenantKey{}, "tenant.id", nil)
+	otelzap.RegisterContextAttribute(requestIDKey{}, "request.id", func(value any) (log.Value, bool) {
+		id, ok := value.(int)
+		return log.IntValue(id), ok
+	})
+
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithRegisteredContextAttributes(),
+	)
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	logger.Ctx(ctx).Info("Test Message")
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, log.StringValue("acme"), attrs["tenant.id"])
+	assert.NotContains(t, attrs, "request.id")
+
+	ctx = context.WithValue(ctx, requestIDKey{}, 42)
+	logger.Ctx(ctx).Info("Test Message", zap.String("tenant.id", "override"))
+	attrs = recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, log.StringValue("override"), attrs["tenant.id"])
+	assert.Equal(t, log.Int64Value(42), attrs["request.id"])
+
+	logger = otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(recorder))
+	logger.Ctx(ctx).Info("Test Message")
+	assert.NotContains(t, recordAttributes(lastRecord(t, recorder)), "tenant.id")
+}
+
+func TestSpanAttributeMaxValueLength(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(logtest.NewRecorder()),
+		otelzap.WithSpanAttributeMaxValueLength(4),
+	)
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	logger.Ctx(ctx).Warn("Test Message", zap.String("short", "abc"), zap.String("long", "abcdef"), zap.String("runes", "äöü"))
+	span.End()
+
+	require.Len(t, spans.Ended(), 1)
+	attrs := map[string]string{}
+	for _, kv := range spans.Ended()[0].Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsString()
+	}
+	assert.Equal(t, "abc", attrs["short"])
+	assert.Equal(t, "abcd...(truncated)", attrs["long"])
+	assert.Equal(t, "äö...(truncated)", attrs["runes"])
+}
+
+func TestTraceContextInjectedField(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithTraceContextInjectedField("traceparent"),
+	)
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+
+	sc := span.SpanContext()
+	expected := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+
+	logger.Ctx(ctx).Info("Test Message")
+	assert.Equal(t, log.StringValue(expected), recordAttributes(lastRecord(t, recorder))["traceparent"])
+	require.Equal(t, 1, observed.Len())
+	assert.Equal(t, expected, observed.All()[0].ContextMap()["traceparent"])
+
+	logger.Ctx(context.Background()).Info("Test Message")
+	assert.NotContains(t, recordAttributes(lastRecord(t, recorder)), "traceparent")
+	assert.NotContains(t, observed.All()[1].ContextMap(), "traceparent")
+}
+
+func TestCallerAttributes(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithCallerAttributes(true, false, false),
+	)
+
+	logger.Ctx(context.Background()).Info("Test Message")
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Contains(t, attrs["code.function"].AsString(), "TestCallerAttributes")
+	assert.NotContains(t, attrs, "code.filepath")
+	assert.NotContains(t, attrs, "code.lineno")
+}
+
+func TestCallerSkipPackages(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(recorder), otelzap.WithCallerSkipPackages())
+
+	// Called by another package, the handler is still reported as caller.
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		logger.Ctx(context.Background()).Info("Test Message")
+	})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Contains(t, attrs["code.function"].AsString(), "TestCallerSkipPackages.func")
+	assert.True(t, strings.HasSuffix(attrs["code.filepath"].AsString(), "logger_test.go"))
+
+	// With the test package as wrapper, its frames are skipped.
+	wrapped := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithCallerSkipPackages("github.com/spechtlabs/go-otel-utils/otelzap_test"),
+	)
+	handler = func(http.ResponseWriter, *http.Request) {
+		logHelper(wrapped, "Test Message")
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	attrs = recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, "net/http.HandlerFunc.ServeHTTP", attrs["code.function"].AsString())
+}
+
+func logHelper(logger *otelzap.Logger, msg string) {
+	logger.Ctx(context.Background()).Info(msg)
+}
+
+func TestCallerDepth(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core, zap.AddCaller()),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithCallerDepth(1),
+	)
+
+	_, _, line, _ := runtime.Caller(0)
+	logHelper(logger, "Test Message")
+
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Contains(t, attrs["code.function"].AsString(), "TestCallerDepth")
+	assert.Equal(t, int64(line+1), attrs["code.lineno"].AsInt64())
+
+	require.Equal(t, 1, observed.Len())
+	caller := observed.All()[0].Caller
+	assert.Contains(t, caller.Function, "TestCallerDepth")
+	assert.Equal(t, line+1, caller.Line)
+}
+
+func TestCallerAgreement(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name string
+		log  func(l *otelzap.Logger)
+	}{
+		{name: "Ctx.Info", log: func(l *otelzap.Logger) { l.Ctx(ctx).Info("Test Message") }},
+		{name: "Ctx.Count", log: func(l *otelzap.Logger) { l.Ctx(ctx).Count("Test Message", "test") }},
+		{name: "InfoContext", log: func(l *otelzap.Logger) { l.InfoContext(ctx, "Test Message") }},
+		{name: "LogContext", log: func(l *otelzap.Logger) { l.LogContext(ctx, zap.InfoLevel, "Test Message") }},
+		{name: "Ctx.Sugar.Infow", log: func(l *otelzap.Logger) { l.Ctx(ctx).Sugar().Infow("Test Message", "foo", "bar") }},
+		{name: "Ctx.Sugar.Infof", log: func(l *otelzap.Logger) { l.Ctx(ctx).Sugar().Infof("Test %s", "Message") }},
+		{name: "Sugar.InfowContext", log: func(l *otelzap.Logger) { l.Sugar().InfowContext(ctx, "Test Message", "foo", "bar") }},
+		{name: "Sugar.InfofContext", log: func(l *otelzap.Logger) { l.Sugar().InfofContext(ctx, "Test %s", "Message") }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			core, observed := observer.New(zapcore.DebugLevel)
+			recorder := logtest.NewRecorder()
+			logger := otelzap.New(zap.New(core, zap.AddCaller()), otelzap.WithLoggerProvider(recorder))
+
+			tt.log(logger)
+
+			require.Equal(t, 1, observed.Len())
+			caller := observed.All()[0].Caller
+			assert.Contains(t, caller.Function, "TestCallerAgreement")
+			assert.Contains(t, caller.File, "logger_test.go")
+
+			attrs := recordAttributes(lastRecord(t, recorder))
+			assert.Equal(t, caller.Function, attrs["code.function"].AsString())
+			assert.Equal(t, caller.File, attrs["code.filepath"].AsString())
+			assert.Equal(t, int64(caller.Line), attrs["code.lineno"].AsInt64())
+		})
+	}
+}
+
+func TestTemplateField(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name string
+		on   bool
+	}{
+		{name: "disabled", on: false},
+		{name: "enabled", on: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			core, observed := observer.New(zapcore.DebugLevel)
+			recorder := logtest.NewRecorder()
+			logger := otelzap.New(zap.New(core),
+				otelzap.WithLoggerProvider(recorder),
+				otelzap.WithTemplateField(tt.on),
+			)
+
+			logger.Ctx(ctx).Sugar().Infof("Hello %s", "World")
+			logger.Sugar().Infof("Hello %s", "World")
+
+			for _, scope := range recorder.Result() {
+				require.Len(t, scope.Records, 2)
+				for _, record := range scope.Records {
+					assert.Equal(t, "Hello World", record.Body().AsString())
+					assert.Equal(t, "Hello %s", recordAttributes(record)["log.template"].AsString())
+				}
+			}
+
+			require.Equal(t, 2, observed.Len())
+			for _, entry := range observed.All() {
+				assert.Equal(t, "Hello World", entry.Message)
+				if tt.on {
+					assert.Equal(t, map[string]interface{}{"log.template": "Hello %s"}, entry.ContextMap())
+				} else {
+					assert.Empty(t, entry.ContextMap())
+				}
+			}
+		})
+	}
+}
+
+func TestZapOptions(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+
+	var hooked []string
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithZapOptions(
+			zap.Hooks(func(entry zapcore.Entry) error {
+				hooked = append(hooked, entry.Message)
+				return nil
+			}),
+			zap.Fields(zap.String("service", "test")),
+		),
+	)
+
+	logger.Ctx(context.Background()).Info("Test Message", zap.String("foo", "bar"))
+	assert.Equal(t, []string{"Test Message"}, hooked)
+
+	require.Equal(t, 1, observed.Len())
+	assert.Equal(t, []zapcore.Field{zap.String("service", "test"), zap.String("foo", "bar")}, observed.All()[0].Context)
+
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, "test", attrs["service"].AsString())
+	assert.Equal(t, "bar", attrs["foo"].AsString())
+}
+
+func TestOtelSampler(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+
+	var n int
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithOtelSampler(func(lvl zapcore.Level, msg string) bool {
+			if lvl >= zap.WarnLevel {
+				return true
+			}
+			n++
+			return n%2 == 1
+		}),
+	)
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		logger.Ctx(ctx).Info("Info Message")
+	}
+	logger.Ctx(ctx).Warn("Warn Message")
+
+	var bodies []string
+	for _, scope := range recorder.Result() {
+		for _, record := range scope.Records {
+			bodies = append(bodies, record.Body().AsString())
+		}
+	}
+	assert.Equal(t, []string{"Info Message", "Info Message", "Warn Message"}, bodies)
+	assert.Equal(t, 5, observed.Len())
+}
+
+func TestSeverityRouting(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop().Named("app"),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithSeverityRouting(map[zapcore.Level]string{
+			zap.ErrorLevel: "app.errors",
+		}),
+	)
+
+	ctx := context.Background()
+	logger.Ctx(ctx).Info("Info Message")
+	logger.Ctx(ctx).Error("Error Message")
+
+	bodies := map[string][]string{}
+	for _, scope := range recorder.Result() {
+		for _, record := range scope.Records {
+			bodies[scope.Name] = append(bodies[scope.Name], record.Body().AsString())
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"app":        {"Info Message"},
+		"app.errors": {"Error Message"},
+	}, bodies)
+}
+
+func TestInstrumentationAttributes(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithInstrumentationAttributes(attribute.String("component", "billing")),
+	)
+
+	logger.Ctx(context.Background()).Info("Test Message")
+
+	scopes := recorder.Result()
+	require.Len(t, scopes, 1)
+	assert.Equal(t, attribute.NewSet(attribute.String("component", "billing")), scopes[0].Attributes)
+}
+
+func TestCheck(t *testing.T) {
+	core, observed := observer.New(zapcore.WarnLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core, zap.AddCaller()), otelzap.WithLoggerProvider(recorder))
+
+	assert.False(t, logger.Enabled(zap.DebugLevel))
+	assert.True(t, logger.Enabled(zap.InfoLevel), "enabled for OTel only")
+	assert.True(t, logger.Enabled(zap.WarnLevel))
+
+	assert.Nil(t, logger.Check(zap.DebugLevel, "Debug Message"))
+	logger.Check(zap.DebugLevel, "Debug Message").Write(zap.String("foo", "bar"))
+
+	ce := logger.Ctx(context.Background()).Check(zap.WarnLevel, "Warn Message")
+	require.NotNil(t, ce)
+	ce.Write(zap.String("foo", "bar"))
+
+	require.Equal(t, 1, observed.Len())
+	entry := observed.All()[0]
+	assert.Equal(t, "Warn Message", entry.Message)
+	assert.Contains(t, entry.Caller.Function, "TestCheck")
+
+	record := lastRecord(t, recorder)
+	assert.Equal(t, "Warn Message", record.Body().AsString())
+	attrs := recordAttributes(record)
+	assert.Equal(t, "bar", attrs["foo"].AsString())
+	assert.Equal(t, entry.Caller.Function, attrs["code.function"].AsString())
+}
+
+func TestEmitOnlyWithinSpan(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithEmitOnlyWithinSpan(),
+	)
+
+	logger.Ctx(context.Background()).Info("Background Message")
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	logger.Ctx(ctx).Info("Span Message")
+	span.End()
+
+	var bodies []string
+	for _, scope := range recorder.Result() {
+		for _, record := range scope.Records {
+			bodies = append(bodies, record.Body().AsString())
+		}
+	}
+	assert.Equal(t, []string{"Span Message"}, bodies)
+	assert.Equal(t, 2, observed.Len())
+}
+
+func TestTraceIDFields(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithTraceIDFields(true),
+	)
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+
+	logger.Ctx(ctx).Info("Test Message")
+	require.Equal(t, 1, observed.Len())
+	fields := observed.All()[0].ContextMap()
+	assert.Equal(t, span.SpanContext().TraceID().String(), fields["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), fields["span_id"])
+	assert.NotContains(t, recordAttributes(lastRecord(t, recorder)), "trace_id")
+
+	logger.Ctx(context.Background()).Info("Test Message")
+	assert.NotContains(t, observed.All()[1].ContextMap(), "trace_id")
+}
+
+func TestBaggageFields(t *testing.T) {
+	tenant, err := baggage.NewMember("tenant.id", "acme")
+	require.NoError(t, err)
+	request, err := baggage.NewMember("request.id", "42")
+	require.NoError(t, err)
+	bag, err := baggage.New(tenant, request)
+	require.NoError(t, err)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	tests := []struct {
+		name   string
+		keys   []string
+		expect map[string]string
+	}{
+		{name: "all members", expect: map[string]string{"tenant.id": "acme", "request.id": "42"}},
+		{name: "selected members", keys: []string{"tenant.id", "missing"}, expect: map[string]string{"tenant.id": "acme"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, observed := observer.New(zapcore.DebugLevel)
+			recorder := logtest.NewRecorder()
+			logger := otelzap.New(zap.New(core),
+				otelzap.WithLoggerProvider(recorder),
+				otelzap.WithBaggageFields(tt.keys...),
+			)
+
+			logger.Ctx(ctx).Info("Test Message")
+
+			attrs := recordAttributes(lastRecord(t, recorder))
+			fields := observed.All()[0].ContextMap()
+			for _, key := range []string{"tenant.id", "request.id", "missing"} {
+				value, ok := tt.expect[key]
+				if !ok {
+					assert.NotContains(t, attrs, key)
+					assert.NotContains(t, fields, key)
+					continue
+				}
+				assert.Equal(t, log.StringValue(value), attrs[key])
+				assert.Equal(t, value, fields[key])
+			}
+		})
+	}
+}
+
+func TestWithConcurrent(t *testing.T) {
+	core, observed := observer.New(zapcore.InfoLevel)
+	logger := otelzap.New(zap.New(core), otelzap.WithLoggerProvider(logtest.NewRecorder()))
+	undo := otelzap.ReplaceGlobals(logger)
+	defer undo()
+
+	const goroutines, iterations = 16, 100
+
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range iterations {
+				otelzap.L().With(zap.Int("goroutine", i), zap.Int("iteration", j)).
+					Info("Test Message", zap.Int("field", i))
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries := observed.All()
+	require.Len(t, entries, goroutines*iterations)
+	for _, entry := range entries {
+		fields := entry.ContextMap()
+		assert.Equal(t, fields["field"], fields["goroutine"])
+		assert.Len(t, entry.Context, 3)
+	}
+
+	otelzap.L().Info("Test Message")
+	assert.Len(t, observed.All()[len(entries)].Context, 0)
+}
+
+func TestWithSharedClone(t *testing.T) {
+	core, observed := observer.New(zapcore.InfoLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core), otelzap.WithLoggerProvider(recorder)).
+		With(zap.String("request.id", "42"))
+
+	const goroutines, iterations = 16, 100
+
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				logger.Ctx(context.Background()).Info("Test Message")
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries := observed.All()
+	require.Len(t, entries, goroutines*iterations)
+	for _, entry := range entries {
+		assert.Equal(t, "42", entry.ContextMap()["request.id"])
+	}
+	assert.Equal(t, log.StringValue("42"), recordAttributes(lastRecord(t, recorder))["request.id"])
+}
+
+func TestLogMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(logtest.NewRecorder()),
+		otelzap.WithLogMetrics(meter),
+	)
+
+	ctx := context.Background()
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	assert.Empty(t, rm.ScopeMetrics, "counter must be created lazily")
+
+	logger.Ctx(ctx).Debug("below the minimal level")
+	logger.Ctx(ctx).Info("Test Message")
+	logger.Ctx(ctx).Error("Test Message")
+	logger.Ctx(ctx).Error("Test Message")
+
+	require.NoError(t, reader.Collect(ctx, &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+
+	m := rm.ScopeMetrics[0].Metrics[0]
+	assert.Equal(t, "log.records", m.Name)
+
+	sum, ok := m.Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+
+	counts := make(map[string]int64)
+	for _, dp := range sum.DataPoints {
+		severity, ok := dp.Attributes.Value("severity")
+		require.True(t, ok)
+		counts[severity.AsString()] = dp.Value
+	}
+	assert.Equal(t, map[string]int64{"INFO": 1, "ERROR": 2}, counts)
+
+	// A nil meter disables counting for the clone only.
+	logger.Clone(otelzap.WithLogMetrics(nil)).Ctx(ctx).Error("Test Message")
+	logger.Ctx(ctx).Info("Test Message")
+
+	require.NoError(t, reader.Collect(ctx, &rm))
+	sum = rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	for _, dp := range sum.DataPoints {
+		severity, _ := dp.Attributes.Value("severity")
+		counts[severity.AsString()] = dp.Value
+	}
+	assert.Equal(t, map[string]int64{"INFO": 2, "ERROR": 2}, counts)
+}
+
+func TestCloneZapLevel(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	logger := otelzap.New(zap.New(core, zap.AddCaller()), otelzap.WithZapLevel(zap.InfoLevel))
+	clone := logger.Clone(otelzap.WithZapLevel(zap.ErrorLevel))
+
+	assert.Equal(t, zap.ErrorLevel, clone.ZapLevel())
+	assert.Equal(t, zap.InfoLevel, logger.ZapLevel())
+
+	logger.Info("parent")
+	clone.Info("clone dropped")
+	clone.Error("clone")
+
+	clone.SetZapLevel(zap.DebugLevel)
+	logger.Debug("parent dropped")
+
+	messages := make([]string, 0, observed.Len())
+	for _, entry := range observed.All() {
+		messages = append(messages, entry.Message)
+		assert.Contains(t, entry.Caller.File, "logger_test.go")
+	}
+	assert.Equal(t, []string{"parent", "clone"}, messages)
+}
+
+func TestSetMinLevel(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(recorder))
+	ctx := context.Background()
+
+	assert.Equal(t, zap.InfoLevel, logger.MinLevel())
+	logger.Ctx(ctx).Debug("dropped")
+	assert.Empty(t, recorder.Result()[0].Records)
+
+	logger.SetMinLevel(zap.DebugLevel)
+	assert.Equal(t, zap.DebugLevel, logger.MinLevel())
+	logger.Ctx(ctx).Debug("emitted")
+	record := lastRecord(t, recorder)
+	assert.Equal(t, "emitted", record.Body().AsString())
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 100 {
+			logger.SetMinLevel(zapcore.Level(i%3 - 1))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			logger.Ctx(ctx).Debug("Test Message")
+		}
+	}()
+	wg.Wait()
+}
+
+func TestCloneMinLevel(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(recorder))
+	clone := logger.Clone(otelzap.WithMinLevel(zap.ErrorLevel))
+
+	assert.Equal(t, zap.ErrorLevel, clone.MinLevel())
+	assert.Equal(t, zap.InfoLevel, logger.MinLevel())
+
+	clone.SetMinLevel(zap.DebugLevel)
+	assert.Equal(t, zap.InfoLevel, logger.MinLevel())
+
+	logger.Info("emitted")
+	record := lastRecord(t, recorder)
+	assert.Equal(t, "emitted", record.Body().AsString())
+}
+
+func TestSpanEvents(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(logtest.NewRecorder()),
+		otelzap.WithSpanEvents(true),
+	)
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	logger.Ctx(ctx).Info("below the annotate level", zap.String("foo", "bar"))
+	logger.Ctx(ctx).Warn("Test Message", zap.String("foo", "bar"))
+	span.End()
+
+	require.Len(t, spans.Ended(), 1)
+	ended := spans.Ended()[0]
+	assert.Empty(t, ended.Attributes())
+
+	require.Len(t, ended.Events(), 1)
+	event := ended.Events()[0]
+	assert.Equal(t, "Test Message", event.Name)
+	assert.Contains(t, event.Attributes, attribute.String("foo", "bar"))
+}
+
+func TestSpanHook(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	var (
+		levels []zapcore.Level
+		fields []zapcore.Field
+	)
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(logtest.NewRecorder()),
+		otelzap.WithExtraFields(zap.String("extra", "field")),
+		otelzap.WithSpanHook(func(span trace.Span, lvl zapcore.Level, msg string, fs []zapcore.Field) {
+			levels = append(levels, lvl)
+			fields = fs
+			span.SetAttributes(attribute.String("hooked", msg))
+		}),
+	)
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	logger.Ctx(ctx).Info("info message")
+	logger.Ctx(ctx).Error("Test Message", zap.String("foo", "bar"))
+	span.End()
+
+	assert.Equal(t, []zapcore.Level{zap.InfoLevel, zap.ErrorLevel}, levels)
+	assert.Equal(t, []zapcore.Field{zap.String("foo", "bar"), zap.String("extra", "field")}, fields)
+
+	require.Len(t, spans.Ended(), 1)
+	ended := spans.Ended()[0]
+	assert.Equal(t, []attribute.KeyValue{attribute.String("hooked", "Test Message")}, ended.Attributes())
+	assert.Equal(t, codes.Unset, ended.Status().Code)
+	assert.Empty(t, ended.Events())
+}
+
+type testError struct{}
+
+func (testError) Error() string { return "test error" }
+
+func TestRecordError(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(logtest.NewRecorder()),
+	)
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	logger.Ctx(ctx).Error("Test Message", zap.Error(testError{}))
+	logger.Ctx(ctx).Error("Test Message")
+	span.End()
+
+	require.Len(t, spans.Ended(), 1)
+	events := spans.Ended()[0].Events()
+	require.Len(t, events, 2)
+	assert.Contains(t, events[0].Attributes, attribute.String("exception.type", "github.com/spechtlabs/go-otel-utils/otelzap_test.testError"))
+	assert.Contains(t, events[0].Attributes, attribute.String("exception.message", "test error"))
+	assert.Contains(t, events[1].Attributes, attribute.String("exception.message", "Test Message"))
+}
+
+func TestClock(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithClock(func() time.Time { return now }),
+	)
+
+	logger.Ctx(context.Background()).Info("Info Message")
+
+	record := lastRecord(t, recorder)
+	assert.Equal(t, now, record.Timestamp())
+	assert.Equal(t, now, record.ObservedTimestamp())
+}
+
+func TestObservedClock(t *testing.T) {
+	occurred := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	observed := occurred.Add(time.Minute)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithClock(func() time.Time { return occurred }),
+		otelzap.WithObservedClock(func() time.Time { return observed }),
+	)
+
+	logger.Ctx(context.Background()).Info("Info Message")
+
+	record := lastRecord(t, recorder)
+	assert.Equal(t, occurred, record.Timestamp())
+	assert.Equal(t, observed, record.ObservedTimestamp())
+}
+
+func TestNamed(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop().Named("app"), otelzap.WithLoggerProvider(recorder))
+
+	named := logger.Named("db")
+	assert.Equal(t, "app.db", named.Name())
+
+	named.Ctx(context.Background()).Info("Info Message")
+	logger.Ctx(context.Background()).Info("Other Message")
+
+	scopes := map[string][]string{}
+	for _, scope := range recorder.Result() {
+		for _, record := range scope.Records {
+			scopes[scope.Name] = append(scopes[scope.Name], record.Body().AsString())
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"app":    {"Other Message"},
+		"app.db": {"Info Message"},
+	}, scopes)
+}
+
+func TestEmitAnnotated(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	recorder := logtest.NewRecorder()
+
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithSpanEvents(true),
+		otelzap.WithEmitAnnotated(false),
+	)
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	logger.Ctx(ctx).Info("Info Message")
+	logger.Ctx(ctx).Warn("Warn Message")
+	span.End()
+	logger.Ctx(context.Background()).Warn("Warn Message without span")
+
+	var bodies []string
+	for _, scope := range recorder.Result() {
+		for _, record := range scope.Records {
+			bodies = append(bodies, record.Body().AsString())
+		}
+	}
+	assert.Equal(t, []string{"Info Message", "Warn Message without span"}, bodies)
+
+	require.Len(t, spans.Ended(), 1)
+	events := spans.Ended()[0].Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "Warn Message", events[0].Name)
+}
+
+func TestSeverityMapper(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithSeverityMapper(func(lvl zapcore.Level) log.Severity {
+			if lvl == zap.WarnLevel {
+				return log.SeverityWarn2
+			}
+			return log.SeverityInfo4
+		}),
+	)
+
+	logger.Ctx(context.Background()).Warn("Test Message")
+	record := lastRecord(t, recorder)
+	assert.Equal(t, log.SeverityWarn2, record.Severity())
+
+	logger.Ctx(context.Background()).Error("Test Message")
+	record = lastRecord(t, recorder)
+	assert.Equal(t, log.SeverityInfo4, record.Severity())
+
+	logger = otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(recorder), otelzap.WithSeverityMapper(nil))
+	logger.Ctx(context.Background()).Warn("Test Message")
+	record = lastRecord(t, recorder)
+	assert.Equal(t, log.SeverityWarn, record.Severity())
+}
+
+func TestConvert(t *testing.T) {
+	assert.Equal(t, log.SeverityDebug, otelzap.ConvertLevel(zap.DebugLevel))
+	assert.Equal(t, log.SeverityError, otelzap.ConvertLevel(zap.ErrorLevel))
+	assert.Equal(t, log.SeverityFatal3, otelzap.ConvertLevel(zap.FatalLevel))
+
+	kvs := otelzap.ConvertFields([]zapcore.Field{
+		zap.String("foo", "bar"),
+		zap.Int("count", 3),
+		zap.Error(errors.New("failure")),
+	})
+	assert.Equal(t, []log.KeyValue{
+		log.String("foo", "bar"),
+		log.Int64("count", 3),
+		log.String("exception.type", "*errors.errorString"),
+		log.String("exception.message", "failure"),
+	}, kvs)
+}
+
+func TestSmithyLogger(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core, zap.AddCaller()),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithMinLevel(zap.DebugLevel),
+		otelzap.WithExplicitTraceCorrelation(true),
+	)
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "GetObject")
+
+	// The waiter middleware logs each attempt through middleware.GetLogger,
+	// which wraps the logger with logging.WithContext, like the SDK clients.
+	ctx = middleware.SetLogger(ctx, logger.SmithyLogger())
+	next := middleware.InitializeHandlerFunc(func(
+		context.Context, middleware.InitializeInput,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		return middleware.InitializeOutput{}, middleware.Metadata{}, nil
+	})
+	_, _, err := (&waiter.Logger{Attempt: 2}).HandleInitialize(ctx, middleware.InitializeInput{}, next)
+	require.NoError(t, err)
+	span.End()
+
+	const sdkCaller = "github.com/aws/smithy-go/waiter.(*Logger).HandleInitialize"
+
+	require.Equal(t, 1, observed.Len())
+	entry := observed.All()[0]
+	assert.Equal(t, zap.DebugLevel, entry.Level)
+	assert.Equal(t, "attempting waiter request, attempt count: 2", entry.Message)
+	assert.Equal(t, sdkCaller, entry.Caller.Function)
+
+	record := lastRecord(t, recorder)
+	assert.Equal(t, log.SeverityDebug, record.Severity())
+	attrs := recordAttributes(record)
+	assert.Equal(t, sdkCaller, attrs["code.function"].AsString())
+	assert.Equal(t, span.SpanContext().TraceID().String(), attrs["trace_id"].AsString())
+}
+
+func TestCodeAttributeKeys(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithCodeAttributeKeys("code.function.name", "code.file.path", "code.line.number"),
+	)
+
+	logger.Ctx(context.Background()).Info("Test Message")
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Contains(t, attrs["code.function.name"].AsString(), "TestCodeAttributeKeys")
+	assert.Contains(t, attrs["code.file.path"].AsString(), "logger_test.go")
+	assert.Equal(t, log.KindInt64, attrs["code.line.number"].Kind())
+	for _, key := range []string{"code.function", "code.filepath", "code.lineno"} {
+		assert.NotContains(t, attrs, key)
+	}
+}
+
+func TestExplicitTraceCorrelation(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.NewNop(),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithExplicitTraceCorrelation(true),
+	)
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+
+	logger.Ctx(ctx).Info("Test Message")
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, log.StringValue(span.SpanContext().TraceID().String()), attrs["trace_id"])
+	assert.Equal(t, log.StringValue(span.SpanContext().SpanID().String()), attrs["span_id"])
+	assert.Equal(t, log.StringValue("01"), attrs["trace_flags"])
+
+	logger.Ctx(context.Background()).Info("Test Message")
+	attrs = recordAttributes(lastRecord(t, recorder))
+	assert.NotContains(t, attrs, "trace_id")
+	assert.NotContains(t, attrs, "span_id")
+}
+
+func TestFieldRedactor(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	recorder := logtest.NewRecorder()
+	logger := otelzap.New(zap.New(core),
+		otelzap.WithLoggerProvider(recorder),
+		otelzap.WithExtraFields(zap.String("api_token", "secret")),
+		otelzap.WithFieldRedactor(func(field zapcore.Field) zapcore.Field {
+			switch field.Key {
+			case "password":
+				return zap.String(field.Key, "***")
+			case "api_token":
+				return zap.Skip()
+			}
+			return field
+		}),
+	)
+
+	logger.Ctx(context.Background()).Info("Test Message", zap.String("password", "hunter2"), zap.String("foo", "bar"))
+
+	attrs := recordAttributes(lastRecord(t, recorder))
+	assert.Equal(t, log.StringValue("***"), attrs["password"])
+	assert.Equal(t, log.StringValue("bar"), attrs["foo"])
+	assert.NotContains(t, attrs, "api_token")
+
+	fields := observed.All()[0].ContextMap()
+	assert.Equal(t, "***", fields["password"])
+	assert.Equal(t, "bar", fields["foo"])
+	assert.NotContains(t, fields, "api_token")
+
+	logger.Info("Test Message", zap.String("password", "hunter2"))
+	fields = observed.All()[1].ContextMap()
+	assert.Equal(t, "***", fields["password"])
+	assert.NotContains(t, fields, "api_token")
+}
+
+func TestGlobalSugaredLogger(t *testing.T) {
+	logger := otelzap.New(zap.NewNop())
+	undo := otelzap.ReplaceGlobals(logger)
+
+	assert.Same(t, otelzap.S(), otelzap.S())
+	assert.Same(t, logger, otelzap.S().Desugar())
+
+	undo()
+	assert.NotSame(t, logger, otelzap.S().Desugar())
+	assert.Same(t, otelzap.L(), otelzap.S().Desugar())
+}
+
+func TestReplaceGlobalsConcurrent(t *testing.T) {
+	prev := otelzap.L()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				undo := otelzap.ReplaceGlobals(otelzap.New(zap.NewNop()))
+				require.NotNil(t, otelzap.L())
+				require.NotNil(t, otelzap.S())
+				otelzap.Ctx(context.Background()).Debug("Debug Message")
+				undo()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Interleaved undos may leave any of the replaced loggers installed.
+	otelzap.ReplaceGlobals(prev)
+
+	undo := otelzap.ReplaceGlobals(otelzap.New(zap.NewNop()))
+	undo()
+	assert.Same(t, prev, otelzap.L())
+	assert.Same(t, prev, otelzap.S().Desugar())
+}
+
+func TestNewNop(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	recorder := logtest.NewRecorder()
+	prev := global.GetLoggerProvider()
+	global.SetLoggerProvider(recorder)
+	defer global.SetLoggerProvider(prev)
+
+	undo := otelzap.ReplaceGlobals(otelzap.NewNop())
+	defer undo()
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	otelzap.Ctx(ctx).Error("Test Message", zap.String("foo", "bar"))
+	otelzap.S().Ctx(ctx).Errorw("Test Message", "foo", "bar")
+	span.End()
+
+	for _, scope := range recorder.Result() {
+		assert.Empty(t, scope.Records)
+	}
+
+	require.Len(t, spans.Ended(), 1)
+	assert.Empty(t, spans.Ended()[0].Attributes())
+	assert.Empty(t, spans.Ended()[0].Events())
+	assert.Equal(t, codes.Unset, spans.Ended()[0].Status().Code)
+}
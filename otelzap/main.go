@@ -1,11 +1,24 @@
 package otelzap
 
 import (
+	"bytes"
+	"reflect"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 const numExtraAttr = 5
 
+// maxCallerFrames bounds the number of frames inspected to find the caller
+// with WithCallerSkipPackages.
+const maxCallerFrames = 32
+
+// otelzapPackage is the import path of this package, used to detect frames
+// belonging to it.
+var otelzapPackage = reflect.TypeOf(Logger{}).PkgPath()
+
 func runtimeCaller(skip int) (fn, file string, line int, ok bool) {
 	rpc := make([]uintptr, 1)
 	n := runtime.Callers(skip+1, rpc[:])
@@ -15,3 +28,82 @@ func runtimeCaller(skip int) (fn, file string, line int, ok bool) {
 	frame, _ := runtime.CallersFrames(rpc).Next()
 	return frame.Function, frame.File, frame.Line, frame.PC != 0
 }
+
+// packageCaller finds the caller as the first frame outside of otelzap and the
+// wrapper packages, see WithCallerSkipPackages.
+type packageCaller struct {
+	// wrappers holds the import paths of the wrapper packages.
+	wrappers map[string]bool
+
+	// frames caches the frames of a program counter, which are more than one
+	// if functions are inlined. It is bounded by the code size of the program.
+	frames sync.Map // map[uintptr][]runtime.Frame
+}
+
+func newPackageCaller(wrappers []string) *packageCaller {
+	p := &packageCaller{wrappers: make(map[string]bool, len(wrappers))}
+	for _, pkg := range wrappers {
+		p.wrappers[pkg] = true
+	}
+	return p
+}
+
+func (p *packageCaller) caller(skip int) (fn, file string, line int, ok bool) {
+	var pcs [maxCallerFrames]uintptr
+	n := runtime.Callers(skip+1, pcs[:])
+
+	for _, pc := range pcs[:n] {
+		for _, frame := range p.pcFrames(pc) {
+			if pkg := funcPackage(frame.Function); pkg == otelzapPackage || p.wrappers[pkg] {
+				continue
+			}
+			return frame.Function, frame.File, frame.Line, frame.PC != 0
+		}
+	}
+	return
+}
+
+// pcFrames returns the frames of the program counter, inlined calls first.
+func (p *packageCaller) pcFrames(pc uintptr) []runtime.Frame {
+	if cached, found := p.frames.Load(pc); found {
+		return cached.([]runtime.Frame)
+	}
+
+	var frames []runtime.Frame
+	iter := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := iter.Next()
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+
+	p.frames.Store(pc, frames)
+	return frames
+}
+
+// funcPackage returns the import path of the package of a fully qualified
+// function name, e.g. "github.com/spechtlabs/go-otel-utils/otelzap" for
+// "github.com/spechtlabs/go-otel-utils/otelzap.(*Logger).Info".
+func funcPackage(fn string) string {
+	slash := strings.LastIndex(fn, "/")
+	if dot := strings.Index(fn[slash+1:], "."); dot >= 0 {
+		return fn[:slash+1+dot]
+	}
+	return fn
+}
+
+// goroutineID returns the id of the current goroutine, parsed from the header
+// of its stack trace ("goroutine 42 [running]:").
+func goroutineID() (int64, bool) {
+	var buf [64]byte
+	stack := buf[:runtime.Stack(buf[:], false)]
+	stack = bytes.TrimPrefix(stack, []byte("goroutine "))
+	if i := bytes.IndexByte(stack, ' '); i > 0 {
+		stack = stack[:i]
+	}
+
+	id, err := strconv.ParseInt(string(stack), 10, 64)
+	return id, err == nil
+}
@@ -1,7 +1,15 @@
 package otelzap
 
 import (
+	"context"
+	"maps"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -12,13 +20,46 @@ type Option func(l *Logger)
 // used by a [Core] to create its [log.Logger].
 //
 // By default if this Option is not provided, the Handler will use the global
-// LoggerProvider.
+// LoggerProvider. The global LoggerProvider delegates to the provider set with
+// global.SetLoggerProvider, even if it is set after the Logger was created, so
+// the Logger may be created before the provider is initialized.
 func WithLoggerProvider(provider log.LoggerProvider) Option {
 	return func(l *Logger) {
 		l.provider = provider
 	}
 }
 
+// WithMeterProvider returns an [Option] that configures the
+// [metric.MeterProvider] used to create the counters incremented by
+// [LoggerWithCtx.Count].
+//
+// By default if this Option is not provided, Count does not record any
+// metrics.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(l *Logger) {
+		l.meterProvider = provider
+	}
+}
+
+// WithLogMetrics returns an [Option] that configures the logger to count the
+// records emitted to OTel with the log.records counter of the given
+// [metric.Meter], using the severity of the record as the severity attribute.
+// This allows alerting on the rate of error logs without querying the logs
+// backend.
+//
+// By default if this Option is not provided, no records are counted. A nil
+// meter disables counting again, e.g. for a Logger created with Clone from a
+// counting one, replacing a meter passed to an earlier WithLogMetrics.
+func WithLogMetrics(meter metric.Meter) Option {
+	return func(l *Logger) {
+		if meter == nil {
+			l.recordCounter = nil
+			return
+		}
+		l.recordCounter = newRecordCounter(meter)
+	}
+}
+
 // WithVersion returns an [Option] that configures the version of the
 // [log.Logger] used by a [Core]. The version should be the version of the
 // package that is being logged.
@@ -37,13 +78,40 @@ func WithSchemaURL(schemaURL string) Option {
 	}
 }
 
+// WithInstrumentationAttributes returns an [Option] that configures the
+// instrumentation scope attributes of the [log.Logger] used by a [Core], e.g.
+// to tag the scope with the name of a component, so backends can group the
+// logs of a service by sub-component. They are set on the meter created for
+// WithMeterProvider as well.
+func WithInstrumentationAttributes(attrs ...attribute.KeyValue) Option {
+	return func(l *Logger) {
+		l.scopeAttrs = append(l.scopeAttrs, attrs...)
+	}
+}
+
 // WithMinLevel sets the minimal zap logging level on which the log message
-// is recorded on the span.
+// is recorded on the span and emitted to OTel. It only gates the OTel side;
+// whether the message is written to zap is decided by the level of the zap
+// core and WithZapLevel.
 //
-// The default is >= zap.InfoLevel.
+// The default is >= zap.InfoLevel. Use Logger.SetMinLevel to change it at
+// runtime.
 func WithMinLevel(lvl zapcore.Level) Option {
 	return func(l *Logger) {
-		l.minLevel = lvl
+		l.minLevel.SetLevel(lvl)
+	}
+}
+
+// WithZapLevel sets the minimal level on which the log message is written to
+// zap, independently of WithMinLevel, e.g. to only print warnings to stdout
+// while emitting info messages to OTel. It is applied in addition to the
+// level of the wrapped zap core, so it can't enable levels the core doesn't.
+//
+// The default is >= zap.DebugLevel, i.e. only the level of the zap core
+// applies. Use Logger.SetZapLevel to change it at runtime.
+func WithZapLevel(lvl zapcore.Level) Option {
+	return func(l *Logger) {
+		l.zapLevel.SetLevel(lvl)
 	}
 }
 
@@ -60,13 +128,93 @@ func WithErrorStatusLevel(lvl zapcore.Level) Option {
 // WithAnnotateLevel sets the minimal zap logging level on which
 // spans will be annotated with the log fields as metadata.
 //
-// The default is >= zap.InfoLevel.
+// The default is >= zap.WarnLevel.
 func WithAnnotateLevel(lvl zapcore.Level) Option {
 	return func(l *Logger) {
 		l.minAnnotateLevel = lvl
 	}
 }
 
+// WithEmitAnnotated configures whether messages at or above the level set with
+// WithAnnotateLevel are emitted to OTel as log records when they annotate a
+// recording span. Disable it to rely on the span annotation for these
+// messages, so backends showing both spans and logs don't ingest them twice.
+// Messages logged without a recording span are emitted regardless.
+//
+// Enabled by default.
+func WithEmitAnnotated(on bool) Option {
+	return func(l *Logger) {
+		l.emitAnnotated = on
+	}
+}
+
+// WithTemplateField configures the printf-style methods of the sugared logger,
+// e.g. Infof, InfofContext or Ctx(ctx).Infof, to also add the log.template
+// field to the zap output, not only to the OTel record. This allows grouping
+// formatted log messages by their template. Disabled by default.
+func WithTemplateField(on bool) Option {
+	return func(l *Logger) {
+		l.templateField = on
+	}
+}
+
+// SpanHook annotates the span in the context passed to the logger with a log
+// message, see WithSpanHook. The fields are the fields passed at the log site
+// followed by the fields added by the logger, e.g. with WithFields.
+type SpanHook func(span trace.Span, lvl zapcore.Level, msg string, fields []zapcore.Field)
+
+// WithSpanHook replaces the default annotation of spans, i.e. setting the log
+// fields as attributes (see WithAnnotateLevel and WithSpanEvents) and setting
+// the status to codes.Error (see WithErrorStatusLevel), with the given hook.
+// It is called for every message at or above the level set with WithMinLevel
+// logged with a context holding a recording span, giving full control over
+// how spans are enriched, e.g. to record the original error of a field.
+func WithSpanHook(hook SpanHook) Option {
+	return func(l *Logger) {
+		l.spanHook = hook
+	}
+}
+
+// WithSeverityMapper overrides how zap logging levels are mapped to the
+// severity of the OTel records, e.g. to record zap.WarnLevel as
+// log.SeverityWarn2. A nil mapper restores the default mapping.
+//
+// By default, the zap levels map to log.SeverityDebug, log.SeverityInfo,
+// log.SeverityWarn and log.SeverityError, and zap.DPanicLevel,
+// zap.PanicLevel and zap.FatalLevel to log.SeverityFatal1 to
+// log.SeverityFatal3.
+func WithSeverityMapper(mapper func(zapcore.Level) log.Severity) Option {
+	return func(l *Logger) {
+		if mapper == nil {
+			mapper = ConvertLevel
+		}
+		l.severityMapper = mapper
+	}
+}
+
+// WithSpanEvents configures the logger to annotate spans (see
+// WithAnnotateLevel) with a span event per log message, named after the
+// message and carrying the log fields as attributes, instead of setting the
+// log fields as span attributes. This makes the individual log messages
+// visible on the timeline of the span in trace UIs like Jaeger.
+func WithSpanEvents(on bool) Option {
+	return func(l *Logger) {
+		l.spanEvents = on
+	}
+}
+
+// WithErrorDetailLevel sets the minimal zap logging level on which the chain,
+// stack trace, advice and causes added by WithError are attached to the OTel
+// record. Below it, only the error itself is recorded, which keeps
+// low-severity records lean.
+//
+// The default is >= zap.DebugLevel, i.e. the details are always attached.
+func WithErrorDetailLevel(lvl zapcore.Level) Option {
+	return func(l *Logger) {
+		l.errorDetailLevel = lvl
+	}
+}
+
 // WithCaller configures the logger to annotate each event with the filename,
 // line number, and function name of the caller.
 //
@@ -78,14 +226,86 @@ func WithCaller(on bool) Option {
 	}
 }
 
-// WithCallerDepth allows you to you to adjust the depth of the caller by setting a number greater than 0. It can
-// be useful if you're wrapping this library with your own helper functions.
+// WithCallerAttributes selects which of the caller attributes are added to
+// each event if WithCaller is on: code.function, code.filepath and
+// code.lineno respectively. By default, all three are added.
+func WithCallerAttributes(function, file, line bool) Option {
+	return func(l *Logger) {
+		l.callerFunction = function
+		l.callerFile = file
+		l.callerLine = line
+	}
+}
+
+// WithCodeAttributeKeys overrides the keys of the caller attributes, which
+// default to code.function, code.filepath and code.lineno. Use
+// "code.function.name", "code.file.path" and "code.line.number" to follow
+// newer versions of the semantic conventions. Empty keys keep the current key.
+func WithCodeAttributeKeys(function, filepath, lineno string) Option {
+	return func(l *Logger) {
+		if function != "" {
+			l.codeFunctionKey = function
+		}
+		if filepath != "" {
+			l.codeFilepathKey = filepath
+		}
+		if lineno != "" {
+			l.codeLinenoKey = lineno
+		}
+	}
+}
+
+// WithErrorKeys overrides the keys of the fields added by WithError for the
+// error, its advice and its causes, which default to error, error_advice and
+// error_causes, e.g. to align them with an existing log schema. Empty keys
+// keep the current key.
+func WithErrorKeys(errorKey, adviceKey, causesKey string) Option {
+	return func(l *Logger) {
+		if errorKey != "" {
+			l.errorKey = errorKey
+		}
+		if adviceKey != "" {
+			l.errorAdviceKey = adviceKey
+		}
+		if causesKey != "" {
+			l.errorCausesKey = causesKey
+		}
+	}
+}
+
+// WithCallerDepth sets the number of additional stack frames to skip when
+// reporting the caller, both in the zap entry and in the code attributes of
+// the OTel record. Set it to the number of helper functions wrapping this
+// library, e.g. 1 for a single logging helper, so the call site of the helper
+// is reported instead of the helper itself.
 func WithCallerDepth(depth int) Option {
 	return func(l *Logger) {
+		l.writer = l.writer.WithOptions(zap.AddCallerSkip(depth - l.callerDepth))
 		l.callerDepth = depth
 	}
 }
 
+// WithCallerSkipPackages reports the first frame outside of otelzap and the
+// given wrapper packages as the caller, instead of counting frames with
+// WithCallerDepth. Pass the import paths of the packages wrapping otelzap, e.g.
+// "github.com/acme/log", to report their callers no matter how many wrapper
+// functions are involved. Without packages, the function calling into otelzap
+// is reported, like with WithCallerDepth(0).
+//
+// The wrapper packages are not detected automatically, as a wrapper can't be
+// told apart from code logging directly while being called by another package
+// itself, e.g. an HTTP handler. Only the frames of the given packages are
+// skipped, so such callers are reported correctly.
+//
+// Unlike WithCallerDepth, the stack is walked on every log call, inspecting at
+// most 32 frames. The function, file and line of each program counter are
+// cached, so each frame is only resolved once.
+func WithCallerSkipPackages(packages ...string) Option {
+	return func(l *Logger) {
+		l.packageCaller = newPackageCaller(packages)
+	}
+}
+
 // WithStackTrace configures the logger to capture logs with a stack trace.
 func WithStackTrace(on bool) Option {
 	return func(l *Logger) {
@@ -93,10 +313,284 @@ func WithStackTrace(on bool) Option {
 	}
 }
 
+// WithGoroutineIDAttribute configures the logger to add the id of the logging
+// goroutine to each event as the thread.id attribute, to correlate interleaved
+// logs of concurrent goroutines. Go doesn't expose goroutine ids, so it is
+// parsed from a stack trace taken on every log call. Only use it for debugging.
+func WithGoroutineIDAttribute() Option {
+	return func(l *Logger) {
+		l.goroutineID = true
+	}
+}
+
+// WithSpanAttributeMaxValueLength limits the length of the string values of
+// the attributes added to the span when annotating it (see WithAnnotateLevel)
+// to n bytes. Longer values are truncated and marked with a "...(truncated)"
+// suffix. The OTel record keeps the full values. Disabled by default, or if n
+// is not positive.
+func WithSpanAttributeMaxValueLength(n int) Option {
+	return func(l *Logger) {
+		l.spanAttributeMaxLen = n
+	}
+}
+
+// WithStringifyAttributes configures the logger to convert all attribute
+// values of the OTel records to their string representation.
+//
+// This is a last resort for log backends that only index string attributes,
+// as it loses the type information of numbers, booleans, slices and maps. The
+// zap output is not affected. It is disabled by default.
+func WithStringifyAttributes() Option {
+	return func(l *Logger) {
+		l.stringifyAttributes = true
+	}
+}
+
+// WithFieldRedactor configures the logger to pass every field through the
+// given redactor before it is written to zap or converted to an attribute of
+// the OTel record, so that sensitive values can be masked, or dropped by
+// returning zap.Skip(), before they leave the process. This includes the fields
+// passed at the log site, the extra fields, the fields derived from the
+// context and the values reported for malformed key-value pairs of the sugared
+// logger, but not the attributes registered with RegisterContextAttribute.
+//
+// The fields added with Logger.WithOptions(zap.Fields(...)) are only redacted
+// in the OTel records and span annotations, as zap writes them itself, and the
+// fields added to the zap.Logger before wrapping it aren't redacted at all.
+func WithFieldRedactor(redactor func(zapcore.Field) zapcore.Field) Option {
+	return func(l *Logger) {
+		l.redactor = redactor
+	}
+}
+
+// WithAttributePrecedence configures which attributes of the OTel records are
+// kept if several sources set the same attribute key: only the attributes of
+// the source with the highest precedence are kept. The given sources take
+// precedence in the order given, followed by all other sources in their
+// default order.
+//
+// By default, fields passed at the log site take precedence over fields
+// extracted from the context, over baggage members, over fields accumulated on
+// the logger. The zap output is not affected and contains all fields.
+func WithAttributePrecedence(sources ...AttributeSource) Option {
+	return func(l *Logger) {
+		l.attributeRanks = attributeRanks(sources)
+	}
+}
+
+// WithRegisteredContextAttributes configures the logger to add the context
+// values registered with RegisterContextAttribute to the OTel records, if they
+// are present in the context passed to the logger.
+func WithRegisteredContextAttributes() Option {
+	return func(l *Logger) {
+		l.contextAttributes = true
+	}
+}
+
+// WithBaggageFields configures the logger to add the members of the baggage
+// in the context passed to the logger with the given keys as fields to both
+// the zap output and the OTel record. If no keys are given, all members of the
+// baggage are added.
+func WithBaggageFields(keys ...string) Option {
+	return func(l *Logger) {
+		l.baggageFields = true
+		l.baggageKeys = keys
+	}
+}
+
+// WithTraceIDFields configures the logger to add the trace_id and span_id of
+// the span in the context passed to the logger as fields to the zap output, so
+// that logs written to stdout can be correlated with traces. The OTel record
+// already carries the trace context, so the fields are not added to it.
+func WithTraceIDFields(on bool) Option {
+	return func(l *Logger) {
+		l.traceIDFields = on
+	}
+}
+
+// WithExplicitTraceCorrelation configures the logger to add the trace_id,
+// span_id and trace_flags of the span in the context passed to the logger as
+// attributes to the OTel record.
+//
+// The OTel SDK already correlates records with the span in the context they
+// are emitted with, but the log API doesn't allow setting the trace context of
+// a record explicitly, so this is the only way to keep the correlation with
+// exporters or collectors that drop the context-based one.
+func WithExplicitTraceCorrelation(on bool) Option {
+	return func(l *Logger) {
+		l.traceCorrelation = on
+	}
+}
+
+// WithTraceContextInjectedField configures the logger to add the span context
+// of the context passed to the logger, formatted as W3C traceparent (e.g.
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"), as field with
+// the given key to both the zap output and the OTel record. This allows log
+// shippers reading the zap output to carry the trace context downstream.
+func WithTraceContextInjectedField(key string) Option {
+	return func(l *Logger) {
+		l.traceparentKey = key
+	}
+}
+
+// WithContextFields configures the logger to call extractor with the context
+// passed to the logger on every log call, adding the returned fields to both
+// the zap output and the OTel record, e.g. the user or tenant of the request
+// stored in the context. The fields rank as context fields, see
+// WithAttributePrecedence. Multiple extractors are called in order.
+//
+// For example,
+//
+//	otelzap.WithContextFields(func(ctx context.Context) []zapcore.Field {
+//		if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+//			return []zapcore.Field{zap.String("tenant.id", tenant)}
+//		}
+//		return nil
+//	})
+func WithContextFields(extractor func(context.Context) []zapcore.Field) Option {
+	return func(l *Logger) {
+		l.contextExtractors = append(l.contextExtractors[:len(l.contextExtractors):len(l.contextExtractors)], extractor)
+	}
+}
+
+// WithZapOptions applies the given zap options to the wrapped zap logger when
+// the Logger is created, e.g. zap.Hooks, zap.WrapCore for sampling or
+// zap.AddStacktrace, like calling WithOptions on the created Logger. Fields
+// added with zap.Fields are added to the OTel records as well.
+func WithZapOptions(opts ...zap.Option) Option {
+	return func(l *Logger) {
+		l.zapOptions = append(l.zapOptions, opts...)
+	}
+}
+
+// WithFields configures the logger to add the given fields to every log
+// message, e.g. the component or version. Unlike zap.Fields, they are added
+// to the zap output, the OTel records and the span annotations alike, like
+// the fields added with Logger.With.
+func WithFields(fields ...zapcore.Field) Option {
+	return func(l *Logger) {
+		l.extraFields = append(l.extraFields[:len(l.extraFields):len(l.extraFields)], fields...)
+	}
+}
+
 // WithExtraFields configures the logger to add the given extra fields to structured log messages
-// and the span
+// and the span. It is an alias for WithFields.
 func WithExtraFields(fields ...zapcore.Field) Option {
+	return WithFields(fields...)
+}
+
+// WithEmitTimeout bounds how long emitting a record to OTel may block the log
+// call. This protects request latency when logs are exported synchronously,
+// e.g. with a simple processor.
+//
+// If the timeout is exceeded, the log call returns while the emit keeps
+// running in the background, so the record may still be exported. At most 64
+// such emits are pending at a time: while they are, further records are
+// dropped right away rather than emitted, e.g. if the exporter is stuck. Both
+// are counted in Logger.DroppedEmits.
+//
+// The default is no timeout, which is fine for the batch processor since it
+// only enqueues records on emit.
+func WithEmitTimeout(timeout time.Duration) Option {
+	return func(l *Logger) {
+		l.emitTimeout = timeout
+		if l.emitSlots == nil {
+			l.emitSlots = make(chan struct{}, maxPendingEmits)
+		}
+	}
+}
+
+// WithClock sets the clock the timestamps of the OTel records are taken from,
+// e.g. to make them deterministic in tests or to correct a skewed clock. The
+// timestamp is the time the event occurred at, see WithObservedClock for the
+// time it was observed at.
+//
+// The default is time.Now.
+func WithClock(clock func() time.Time) Option {
+	return func(l *Logger) {
+		l.clock = clock
+	}
+}
+
+// WithObservedClock sets the clock the observed timestamps of the OTel records
+// are taken from, i.e. the time the event was observed by the logger, as
+// opposed to the time it occurred at. Use it along with WithClock when
+// forwarding the logs of delayed sources, backfilling the event time while
+// keeping the observed time current.
+//
+// By default, the observed timestamp equals the timestamp.
+func WithObservedClock(clock func() time.Time) Option {
+	return func(l *Logger) {
+		l.observedClock = clock
+	}
+}
+
+// WithEmitOnlyWithinSpan configures the logger to only emit events to OTel if
+// the context passed to the logger holds a recording span. Events logged
+// without a context, with a context without span, or within a span that is not
+// recording (e.g. sampled out) are only written to zap. Use it to drop the
+// background noise of a service from the OTel export, keeping the logs
+// correlated with traces only.
+func WithEmitOnlyWithinSpan() Option {
+	return func(l *Logger) {
+		l.emitOnlyWithinSpan = true
+	}
+}
+
+// WithSeverityRouting configures the logger to emit the records of the given
+// levels to OTel loggers with the given instrumentation scope names, e.g. to
+// route error logs to a different scope than info logs. Records of levels not
+// in the map are emitted to the OTel logger named after the zap logger.
+func WithSeverityRouting(routes map[zapcore.Level]string) Option {
+	return func(l *Logger) {
+		l.severityRouting = maps.Clone(routes)
+	}
+}
+
+// OtelSampler decides whether a log message is emitted to OTel, see
+// WithOtelSampler.
+type OtelSampler func(lvl zapcore.Level, msg string) bool
+
+// WithOtelSampler configures the logger to only emit the log messages to OTel
+// for which the sampler returns true. It is independent of what is written to
+// zap and of the span annotation, so the local logs stay complete while only a
+// sample of, e.g., high-volume debug logs is exported. The sampler is called
+// concurrently and has to be safe for concurrent use.
+func WithOtelSampler(sampler OtelSampler) Option {
+	return func(l *Logger) {
+		l.otelSampler = sampler
+	}
+}
+
+// WithDedupCache configures the logger to collapse identical records sent to
+// OTel using the given cache. Share the same cache across loggers to collapse
+// identical records logged concurrently through any of them. The zap output is
+// not affected.
+func WithDedupCache(cache *DedupCache) Option {
+	return func(l *Logger) {
+		l.dedup = cache
+	}
+}
+
+// WithDeferLogExportUntilSpanEnd configures the logger to hold back the records
+// logged within a recording span until the span ends. They are then emitted,
+// in the order they were logged, if the span is sampled and dropped otherwise,
+// which aligns the logs with the sampling decision of the trace. The span
+// processor returned by Logger.SpanProcessor must be registered with the
+// tracer provider for the records to be released.
+//
+// Records are kept in memory, including their fields, until their span ends,
+// and are emitted after the records of spans that ended earlier. At most 1000
+// records are held back per span, further ones are emitted right away. Records
+// logged without a recording span are emitted right away as well.
+//
+// To bound the memory held by spans that end late or never, e.g. leaked spans,
+// the records of a span are emitted regardless of sampling once the first of
+// them was held back for 5 minutes, or once 10000 records are held back in
+// total, starting with the span held back the longest. The records of spans
+// still running are dropped on shutdown.
+func WithDeferLogExportUntilSpanEnd() Option {
 	return func(l *Logger) {
-		l.extraFields = append(l.extraFields, fields...)
+		l.spanBuffer = newSpanLogBuffer()
 	}
 }
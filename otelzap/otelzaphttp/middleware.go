@@ -0,0 +1,181 @@
+// Package otelzaphttp provides an HTTP middleware that traces requests, logs
+// them with an otelzap.Logger and makes the logger available to handlers.
+package otelzaphttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// tracerName is the instrumentation scope of the spans started by the
+// middleware.
+const tracerName = "github.com/spechtlabs/go-otel-utils/otelzap/otelzaphttp"
+
+type loggerKey struct{}
+
+type config struct {
+	logger         *otelzap.Logger
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// Option applies a configuration to the middleware.
+type Option func(c *config)
+
+// WithLogger sets the logger requests are logged with and which is stored in
+// the request context. Defaults to the global logger, see otelzap.L.
+func WithLogger(logger *otelzap.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithTracerProvider sets the tracer provider the request spans are started
+// with. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithPropagator sets the propagator the trace context of incoming requests is
+// extracted with. Defaults to the global propagator.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+// Middleware returns a middleware that starts a server span for every request,
+// stores the logger in the request context, see FromContext, and logs the
+// start and the end of the request.
+//
+// The end of the request is logged at ErrorLevel for 5xx responses, at
+// WarnLevel for 4xx responses and at InfoLevel otherwise, so the span status
+// follows the level configured with otelzap.WithErrorStatusLevel.
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	c := config{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := c.logger
+			if logger == nil {
+				logger = otelzap.L()
+			}
+			tp := c.tracerProvider
+			if tp == nil {
+				tp = otel.GetTracerProvider()
+			}
+			propagator := c.propagator
+			if propagator == nil {
+				propagator = otel.GetTextMapPropagator()
+			}
+
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tp.Tracer(tracerName).Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			r = r.WithContext(context.WithValue(ctx, loggerKey{}, logger))
+			log := logger.Ctx(r.Context())
+
+			fields := []zap.Field{
+				zap.String(string(semconv.HTTPRequestMethodKey), r.Method),
+				zap.String(string(semconv.URLPathKey), r.URL.Path),
+			}
+			log.Debug("request started", fields...)
+
+			start := time.Now()
+			rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+			latency := time.Since(start)
+
+			fields = append(fields,
+				zap.Int(string(semconv.HTTPResponseStatusCodeKey), rw.status),
+				zap.Duration("latency", latency),
+			)
+			switch {
+			case rw.status >= http.StatusInternalServerError:
+				log.Error("request finished", fields...)
+			case rw.status >= http.StatusBadRequest:
+				log.Warn("request finished", fields...)
+			default:
+				log.Info("request finished", fields...)
+			}
+
+			// Set after logging, so the attributes aren't overwritten when the
+			// span is annotated with the log fields.
+			if route := route(r.Pattern); route != "" {
+				span.SetName(r.Method + " " + route)
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rw.status))
+		})
+	}
+}
+
+// FromContext returns the logger stored in the request context by the
+// middleware, bound to the given context, so spans started by the handler are
+// annotated as well. If there is none, it returns the global logger bound to
+// the context.
+func FromContext(ctx context.Context) otelzap.LoggerWithCtx {
+	if logger, ok := ctx.Value(loggerKey{}).(*otelzap.Logger); ok {
+		return logger.Ctx(ctx)
+	}
+	return otelzap.L().Ctx(ctx)
+}
+
+// route returns the path of the http.ServeMux pattern the request matched,
+// without the method and host, e.g. "/items/{id}" for "GET /items/{id}".
+func route(pattern string) string {
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = pattern[i+1:]
+	}
+	if i := strings.IndexByte(pattern, '/'); i >= 0 {
+		return pattern[i:]
+	}
+	return ""
+}
+
+// statusRecorder records the status code written to the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped response writer, so http.ResponseController can
+// access its optional interfaces, e.g. http.Flusher.
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,77 @@
+package otelzaphttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"github.com/spechtlabs/go-otel-utils/otelzap/otelzaphttp"
+	"github.com/spechtlabs/go-otel-utils/otelzap/otelzaptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func TestMiddleware(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		status   int
+		severity log.Severity
+		code     codes.Code
+	}{
+		{name: "ok", status: http.StatusOK, severity: log.SeverityInfo, code: codes.Unset},
+		{name: "client error", status: http.StatusNotFound, severity: log.SeverityWarn, code: codes.Unset},
+		{name: "server error", status: http.StatusInternalServerError, severity: log.SeverityError, code: codes.Error},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := tracetest.NewSpanRecorder()
+			provider := otelzaptest.NewRecordingProvider()
+			logger := otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(provider))
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+				otelzaphttp.FromContext(r.Context()).Info("handling request")
+				w.WriteHeader(tt.status)
+			})
+
+			handler := otelzaphttp.Middleware(
+				otelzaphttp.WithLogger(logger),
+				otelzaphttp.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+			)(mux)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/42", nil))
+			assert.Equal(t, tt.status, rec.Code)
+
+			require.Len(t, spans.Ended(), 1)
+			span := spans.Ended()[0]
+			assert.Equal(t, "GET /items/{id}", span.Name())
+			assert.Equal(t, tt.code, span.Status().Code)
+			assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", tt.status))
+
+			records := provider.Records()
+			require.Len(t, records, 2)
+			assert.Equal(t, "handling request", records[0].Body.AsString())
+			assert.Equal(t, span.SpanContext(), trace.SpanContextFromContext(records[0].Context))
+
+			finished := records[1]
+			assert.Equal(t, "request finished", finished.Body.AsString())
+			assert.Equal(t, tt.severity, finished.Severity)
+			status, ok := finished.Attribute("http.response.status_code")
+			require.True(t, ok)
+			assert.Equal(t, int64(tt.status), status.AsInt64())
+			path, ok := finished.Attribute("url.path")
+			require.True(t, ok)
+			assert.Equal(t, "/items/42", path.AsString())
+			_, ok = finished.Attribute("latency")
+			assert.True(t, ok)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Package otelzaptest provides helpers to test the OTel log records emitted by
+// an otelzap.Logger.
+package otelzaptest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/embedded"
+)
+
+// Record is a log record captured by a RecordingProvider.
+type Record struct {
+	// Context is the context the record was emitted with.
+	Context context.Context
+	// Scope is the name of the logger the record was emitted with.
+	Scope string
+
+	Timestamp         time.Time
+	ObservedTimestamp time.Time
+	Severity          log.Severity
+	SeverityText      string
+	Body              log.Value
+	Attributes        []log.KeyValue
+}
+
+// Attribute returns the value of the attribute with the given key, and whether
+// the record has such an attribute.
+func (r Record) Attribute(key string) (log.Value, bool) {
+	for _, kv := range r.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return log.Value{}, false
+}
+
+// RecordingProvider is a log.LoggerProvider capturing every record emitted by
+// its loggers in memory. Pass it to otelzap.WithLoggerProvider to assert on
+// the emitted records in tests. It's safe for concurrent use.
+type RecordingProvider struct {
+	embedded.LoggerProvider
+
+	mu      sync.Mutex
+	records []Record
+}
+
+var _ log.LoggerProvider = (*RecordingProvider)(nil)
+
+// NewRecordingProvider returns an empty RecordingProvider.
+func NewRecordingProvider() *RecordingProvider {
+	return &RecordingProvider{}
+}
+
+// Logger returns a logger capturing the records emitted with it.
+func (p *RecordingProvider) Logger(name string, _ ...log.LoggerOption) log.Logger {
+	return &recordingLogger{provider: p, scope: name}
+}
+
+// Records returns a copy of the captured records, in the order they were
+// emitted.
+func (p *RecordingProvider) Records() []Record {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Record(nil), p.records...)
+}
+
+// LastRecord returns the record emitted last, and whether any record was
+// emitted at all.
+func (p *RecordingProvider) LastRecord() (Record, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.records) == 0 {
+		return Record{}, false
+	}
+	return p.records[len(p.records)-1], true
+}
+
+// Reset drops the captured records.
+func (p *RecordingProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.records = nil
+}
+
+func (p *RecordingProvider) add(record Record) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.records = append(p.records, record)
+}
+
+type recordingLogger struct {
+	embedded.Logger
+
+	provider *RecordingProvider
+	scope    string
+}
+
+func (l *recordingLogger) Emit(ctx context.Context, record log.Record) {
+	attrs := make([]log.KeyValue, 0, record.AttributesLen())
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs = append(attrs, kv)
+		return true
+	})
+
+	l.provider.add(Record{
+		Context:           ctx,
+		Scope:             l.scope,
+		Timestamp:         record.Timestamp(),
+		ObservedTimestamp: record.ObservedTimestamp(),
+		Severity:          record.Severity(),
+		SeverityText:      record.SeverityText(),
+		Body:              record.Body(),
+		Attributes:        attrs,
+	})
+}
+
+func (l *recordingLogger) Enabled(context.Context, log.EnabledParameters) bool {
+	return true
+}
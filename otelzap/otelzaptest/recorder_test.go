@@ -0,0 +1,66 @@
+package otelzaptest_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"github.com/spechtlabs/go-otel-utils/otelzap/otelzaptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/log"
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+func TestRecordingProvider(t *testing.T) {
+	provider := otelzaptest.NewRecordingProvider()
+	logger := otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(provider))
+
+	_, ok := provider.LastRecord()
+	assert.False(t, ok)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	logger.Ctx(ctx).Warn("Test Message", zap.String("foo", "bar"), zap.Int("count", 3))
+
+	record, ok := provider.LastRecord()
+	require.True(t, ok)
+	assert.Equal(t, "Test Message", record.Body.AsString())
+	assert.Equal(t, log.SeverityWarn, record.Severity)
+	assert.Equal(t, "value", record.Context.Value(ctxKey{}))
+
+	foo, ok := record.Attribute("foo")
+	require.True(t, ok)
+	assert.Equal(t, "bar", foo.AsString())
+
+	count, ok := record.Attribute("count")
+	require.True(t, ok)
+	assert.Equal(t, int64(3), count.AsInt64())
+
+	_, ok = record.Attribute("code.function")
+	assert.True(t, ok)
+
+	provider.Reset()
+	assert.Empty(t, provider.Records())
+}
+
+func TestRecordingProviderConcurrent(t *testing.T) {
+	provider := otelzaptest.NewRecordingProvider()
+	logger := otelzap.New(zap.NewNop(), otelzap.WithLoggerProvider(provider))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				logger.Ctx(context.Background()).Info("Test Message")
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, provider.Records(), 80)
+}
@@ -0,0 +1,98 @@
+package otelzap
+
+import (
+	"go.opentelemetry.io/otel/log"
+)
+
+// AttributeSource is a source of the attributes of the OTel records. If the
+// same attribute key is set by several sources, only the attributes of the
+// source with the highest precedence are kept, see WithAttributePrecedence.
+type AttributeSource int
+
+const (
+	// FieldsSource holds the fields passed at the log site.
+	FieldsSource AttributeSource = iota
+	// ContextSource holds the fields extracted from the context.
+	ContextSource
+	// BaggageSource holds the baggage members of the context.
+	BaggageSource
+	// ExtraFieldsSource holds the fields accumulated on the logger, e.g. with
+	// WithExtraFields, WithOptions(zap.Fields(...)) or With.
+	ExtraFieldsSource
+
+	numAttributeSources
+)
+
+// attributeSources holds the attributes of a record per source.
+type attributeSources [numAttributeSources][]log.KeyValue
+
+// defaultAttributeRanks gives the sources precedence in the order they are
+// declared in, i.e. fields > context > baggage > extra fields.
+var defaultAttributeRanks = [numAttributeSources]int{0, 1, 2, 3}
+
+// attributeRanks returns the rank of each source, the lower the rank the
+// higher its precedence, with the given sources first and all others in their
+// default order.
+func attributeRanks(sources []AttributeSource) [numAttributeSources]int {
+	var ranks [numAttributeSources]int
+	ranked := [numAttributeSources]bool{}
+
+	rank := 0
+	for _, src := range sources {
+		if src < 0 || src >= numAttributeSources || ranked[src] {
+			continue
+		}
+		ranks[src] = rank
+		ranked[src] = true
+		rank++
+	}
+
+	for src := AttributeSource(0); src < numAttributeSources; src++ {
+		if !ranked[src] {
+			ranks[src] = rank
+			rank++
+		}
+	}
+
+	return ranks
+}
+
+// mergeAttributes returns the attributes of all sources, dropping those whose
+// key is also set by a source with a higher precedence. The attributes keep
+// their order, with the sources in their declaration order.
+func (l *Logger) mergeAttributes(sources attributeSources) []log.KeyValue {
+	size, nonEmpty := 0, 0
+	for _, kvs := range sources {
+		size += len(kvs)
+		if len(kvs) > 0 {
+			nonEmpty++
+		}
+	}
+
+	merged := make([]log.KeyValue, 0, size+numExtraAttr)
+	if nonEmpty <= 1 {
+		for _, kvs := range sources {
+			merged = append(merged, kvs...)
+		}
+		return merged
+	}
+
+	best := make(map[string]int, size)
+	for src, kvs := range sources {
+		for _, kv := range kvs {
+			if rank, ok := best[kv.Key]; !ok || l.attributeRanks[src] < rank {
+				best[kv.Key] = l.attributeRanks[src]
+			}
+		}
+	}
+
+	for src, kvs := range sources {
+		for _, kv := range kvs {
+			if best[kv.Key] == l.attributeRanks[src] {
+				merged = append(merged, kv)
+			}
+		}
+	}
+
+	return merged
+}
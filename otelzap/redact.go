@@ -0,0 +1,36 @@
+package otelzap
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactFields returns the fields as rewritten by the redactor configured with
+// WithFieldRedactor, dropping the ones it turned into zap.Skip. The given slice
+// is not modified.
+func (l *Logger) redactFields(fields []zapcore.Field) []zapcore.Field {
+	if l.redactor == nil || len(fields) == 0 {
+		return fields
+	}
+
+	redacted := make([]zapcore.Field, 0, len(fields))
+	for _, field := range fields {
+		field = l.redactor(field)
+		if field.Type == zapcore.SkipType {
+			continue
+		}
+		redacted = append(redacted, field)
+	}
+
+	return redacted
+}
+
+// redactValue returns the value as field with the given key, as rewritten by
+// the redactor configured with WithFieldRedactor.
+func (l *Logger) redactValue(key string, value interface{}) zapcore.Field {
+	field := zap.Any(key, value)
+	if l.redactor == nil {
+		return field
+	}
+	return l.redactor(field)
+}
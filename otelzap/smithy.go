@@ -0,0 +1,60 @@
+package otelzap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/smithy-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// smithyLogger adapts a Logger to the logging.Logger interface of smithy-go,
+// see Logger.SmithyLogger.
+type smithyLogger struct {
+	l   *Logger
+	ctx context.Context
+}
+
+var (
+	_ logging.Logger        = smithyLogger{}
+	_ logging.ContextLogger = smithyLogger{}
+)
+
+// SmithyLogger returns the Logger as logging.Logger of smithy-go, to be set as
+// the logger of the AWS SDK for Go v2, e.g. with config.WithLogger. The caller
+// reported for its messages is the SDK code logging them, e.g. a middleware
+// calling Logf on the logger returned by middleware.GetLogger. This assumes
+// Logf is called directly on the returned logger or the one returned by its
+// WithContext; wrapping it in another logging.Logger shifts the caller to the
+// wrapper. It implements logging.ContextLogger, so the messages logged during
+// an SDK operation are correlated with its span.
+//
+// Warn messages are logged at WarnLevel, Debug messages, e.g. the retry and
+// request logs enabled with aws.ClientLogMode, at DebugLevel.
+func (l *Logger) SmithyLogger() logging.Logger {
+	return smithyLogger{l: l, ctx: context.Background()}
+}
+
+// Logf logs the message at the level matching the classification.
+func (s smithyLogger) Logf(classification logging.Classification, format string, v ...interface{}) {
+	s.l.Ctx(s.ctx).write(smithyLevel(classification), fmt.Sprintf(format, v...), nil)
+}
+
+// WithContext returns the logger logging with the context.
+func (s smithyLogger) WithContext(ctx context.Context) logging.Logger {
+	return smithyLogger{l: s.l, ctx: ctx}
+}
+
+// smithyLevel returns the zap level of a smithy-go log classification. Unknown
+// classifications are logged at InfoLevel.
+func smithyLevel(classification logging.Classification) zapcore.Level {
+	switch classification {
+	case logging.Warn:
+		return zap.WarnLevel
+	case logging.Debug:
+		return zap.DebugLevel
+	default:
+		return zap.InfoLevel
+	}
+}
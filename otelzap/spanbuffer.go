@@ -0,0 +1,182 @@
+package otelzap
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxBufferedRecordsPerSpan bounds the number of records buffered for a
+// single span. Further records are emitted right away.
+const maxBufferedRecordsPerSpan = 1000
+
+// maxBufferedRecords bounds the number of records buffered for all spans. If
+// it is reached, the records of the span buffered the longest are emitted.
+const maxBufferedRecords = 10000
+
+// maxSpanBufferAge is how long the records of a span are held back at most. The
+// records of spans that didn't end by then, e.g. leaked spans, are emitted.
+const maxSpanBufferAge = 5 * time.Minute
+
+// spanLogBuffer holds back the records logged within a recording span until
+// the span ends, and then emits or drops them depending on whether the span
+// ended up being sampled. It implements sdktrace.SpanProcessor to observe the
+// end of spans.
+type spanLogBuffer struct {
+	mu      sync.Mutex
+	records map[spanKey]*spanRecords
+	// count is the number of records buffered for all spans.
+	count int
+	// evicted is the time the buffer was last checked for expired spans.
+	evicted time.Time
+}
+
+type spanKey struct {
+	traceID trace.TraceID
+	spanID  trace.SpanID
+}
+
+// spanRecords holds the records buffered for a span.
+type spanRecords struct {
+	// since is the time the first record of the span was logged at.
+	since time.Time
+	emits []func()
+}
+
+var _ sdktrace.SpanProcessor = (*spanLogBuffer)(nil)
+
+func newSpanLogBuffer() *spanLogBuffer {
+	return &spanLogBuffer{
+		records: make(map[spanKey]*spanRecords),
+	}
+}
+
+// add buffers the record if ctx carries a recording span and reports whether
+// it did so. It emits the records evicted from the buffer to make room for it
+// or because their span didn't end in time.
+func (b *spanLogBuffer) add(ctx context.Context, record log.Record, emit func(context.Context, log.Record)) bool {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return false
+	}
+
+	// Preserve the time the record was logged at, rather than when it is
+	// eventually emitted.
+	if record.Timestamp().IsZero() {
+		record.SetTimestamp(time.Now())
+	}
+	now := record.Timestamp()
+
+	sc := span.SpanContext()
+	key := spanKey{traceID: sc.TraceID(), spanID: sc.SpanID()}
+
+	b.mu.Lock()
+	evicted := b.evict(now)
+
+	buffered, ok := b.records[key]
+	if ok && len(buffered.emits) >= maxBufferedRecordsPerSpan {
+		b.mu.Unlock()
+		release(evicted)
+		return false
+	}
+
+	if !ok {
+		buffered = &spanRecords{since: now}
+		b.records[key] = buffered
+	}
+	buffered.emits = append(buffered.emits, func() { emit(ctx, record) })
+	b.count++
+	b.mu.Unlock()
+
+	release(evicted)
+	return true
+}
+
+// evict removes the records of the spans buffered for longer than
+// maxSpanBufferAge, checking at most once a second, and of the span buffered
+// the longest if the buffer is full. It returns the removed records to be
+// emitted and must be called with b.mu held.
+func (b *spanLogBuffer) evict(now time.Time) []func() {
+	var evicted []func()
+
+	if now.Sub(b.evicted) >= time.Second {
+		b.evicted = now
+		for key, buffered := range b.records {
+			if now.Sub(buffered.since) >= maxSpanBufferAge {
+				evicted = append(evicted, b.remove(key)...)
+			}
+		}
+	}
+
+	if b.count >= maxBufferedRecords {
+		var oldest spanKey
+		var since time.Time
+		for key, buffered := range b.records {
+			if since.IsZero() || buffered.since.Before(since) {
+				oldest, since = key, buffered.since
+			}
+		}
+		evicted = append(evicted, b.remove(oldest)...)
+	}
+
+	return evicted
+}
+
+// remove removes the records of the span from the buffer and returns them. It
+// must be called with b.mu held.
+func (b *spanLogBuffer) remove(key spanKey) []func() {
+	buffered, ok := b.records[key]
+	if !ok {
+		return nil
+	}
+
+	delete(b.records, key)
+	b.count -= len(buffered.emits)
+	return buffered.emits
+}
+
+// release emits the records.
+func release(emits []func()) {
+	for _, emit := range emits {
+		emit()
+	}
+}
+
+// OnStart does nothing.
+func (b *spanLogBuffer) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd emits the records buffered for the span in the order they were logged
+// if the span is sampled, and drops them otherwise.
+func (b *spanLogBuffer) OnEnd(s sdktrace.ReadOnlySpan) {
+	sc := s.SpanContext()
+	key := spanKey{traceID: sc.TraceID(), spanID: sc.SpanID()}
+
+	b.mu.Lock()
+	records := b.remove(key)
+	b.mu.Unlock()
+
+	if !sc.IsSampled() {
+		return
+	}
+
+	release(records)
+}
+
+// Shutdown drops the records of spans that never ended.
+func (b *spanLogBuffer) Shutdown(context.Context) error {
+	b.mu.Lock()
+	b.records = make(map[spanKey]*spanRecords)
+	b.count = 0
+	b.mu.Unlock()
+	return nil
+}
+
+// ForceFlush does nothing, since records are only released once their span
+// ends.
+func (b *spanLogBuffer) ForceFlush(context.Context) error {
+	return nil
+}